@@ -0,0 +1,371 @@
+package pbs
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ExtendSubjobs is the extend argument to Pbs_statjob which reports the
+// subjobs of job arrays rather than the arrays themselves, as qstat -t does
+const ExtendSubjobs = "t"
+
+// arrayRangeExtend prefixes the indices given to hold, release, delete and
+// alter to select subjobs of an array, as with qdel -t
+const arrayRangeExtend = "array_range="
+
+// MaxArraySize is the most subjobs an ArrayRange can have, as with the
+// server's max_job_array_size, so that a request such as "0-2147483647"
+// is rejected before the subjobs are counted out
+var MaxArraySize = 100000
+
+// ErrArrayTooLarge is returned for array ranges with more than
+// MaxArraySize indices
+var ErrArrayTooLarge = errors.New("pbs: job array is too large")
+
+// ArrayInterval is the indices from First to Last, every Step'th. A Step
+// of zero, or less, is taken as 1.
+type ArrayInterval struct {
+	First int
+	Last  int
+	Step  int
+}
+
+func (iv ArrayInterval) step() int {
+	if iv.Step <= 0 {
+		return 1
+	}
+	return iv.Step
+}
+
+// count returns the number of indices in iv, at most math.MaxInt
+func (iv ArrayInterval) count() int {
+	if iv.Last < iv.First {
+		return 0
+	}
+	n := (uint64(iv.Last)-uint64(iv.First))/uint64(iv.step()) + 1
+	if n == 0 || n > math.MaxInt {
+		return math.MaxInt
+	}
+	return int(n)
+}
+
+func (iv ArrayInterval) contains(index int) bool {
+	return index >= iv.First && index <= iv.Last && (index-iv.First)%iv.step() == 0
+}
+
+// ArrayRange is the indices of a job array, as in ATTR_t, e.g. "1-100%10"
+// for 1 to 100 with at most 10 subjobs running at once, or "0,4,10-15"
+type ArrayRange struct {
+	// Intervals are ascending and don't overlap
+	Intervals []ArrayInterval
+	// Limit is the number of subjobs which can run at once, zero for no
+	// limit
+	Limit int
+}
+
+// ParseArrayRange parses an array request. As well as TORQUE's ranges, a
+// range can have a step, as in PBS Professional, e.g. "0-10:2" for the
+// even indices, which TORQUE itself doesn't accept; SubmitArray and the
+// other array functions list the indices of such ranges for the server.
+// Ranges with more than MaxArraySize indices fail with ErrArrayTooLarge.
+func ParseArrayRange(s string) (ArrayRange, error) {
+	invalid := errors.New("pbs: invalid array range " + strconv.Quote(s))
+
+	var r ArrayRange
+	list, limit, ok := strings.Cut(s, "%")
+	if ok {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			return r, invalid
+		}
+		r.Limit = n
+	}
+
+	var intervals []ArrayInterval
+	for _, part := range strings.Split(list, ",") {
+		bounds, step, stepped := strings.Cut(strings.TrimSpace(part), ":")
+		first, last, ok := strings.Cut(bounds, "-")
+		lo, err := strconv.ParseUint(first, 10, 31)
+		if err != nil {
+			return r, invalid
+		}
+		hi := lo
+		if ok {
+			hi, err = strconv.ParseUint(last, 10, 31)
+			if err != nil || hi < lo {
+				return r, invalid
+			}
+		}
+		iv := ArrayInterval{First: int(lo), Last: int(hi), Step: 1}
+		if stepped {
+			n, err := strconv.ParseUint(step, 10, 31)
+			if err != nil || n < 1 || !ok {
+				return r, invalid
+			}
+			iv.Step = int(n)
+		}
+		intervals = append(intervals, iv)
+	}
+
+	r.Intervals, ok = normalizeIntervals(intervals)
+	if !ok {
+		return ArrayRange{}, errors.New("pbs: overlapping ranges with steps in array range " + strconv.Quote(s))
+	}
+	if r.Count() > MaxArraySize {
+		return ArrayRange{}, ErrArrayTooLarge
+	}
+	return r, nil
+}
+
+// normalizeIntervals sorts intervals, ends each on its last index and
+// merges those without steps which overlap or adjoin. Intervals with steps
+// which overlap others can't be merged, and aren't ok.
+func normalizeIntervals(intervals []ArrayInterval) ([]ArrayInterval, bool) {
+	for i, iv := range intervals {
+		iv.Last -= (iv.Last - iv.First) % iv.Step
+		if iv.First == iv.Last {
+			iv.Step = 1
+		}
+		intervals[i] = iv
+	}
+	sort.Slice(intervals, func(i, j int) bool {
+		return intervals[i].First < intervals[j].First
+	})
+
+	var merged []ArrayInterval
+	for _, iv := range intervals {
+		if len(merged) == 0 {
+			merged = append(merged, iv)
+			continue
+		}
+		prev := &merged[len(merged)-1]
+		switch {
+		case iv.First > prev.Last+1 || iv.First == prev.Last+1 && (iv.Step > 1 || prev.Step > 1):
+			merged = append(merged, iv)
+		case iv.First == iv.Last && prev.contains(iv.First):
+			// A single index which is already in the range
+		case iv.Step > 1 || prev.Step > 1:
+			return nil, false
+		case iv.Last > prev.Last:
+			prev.Last = iv.Last
+		}
+	}
+	return merged, true
+}
+
+// Range returns the ArrayRange of the indices first to last
+func Range(first, last int) ArrayRange {
+	if last < first {
+		return ArrayRange{}
+	}
+	return ArrayRange{Intervals: []ArrayInterval{{First: first, Last: last, Step: 1}}}
+}
+
+// Count returns the number of indices in r, at most math.MaxInt
+func (r ArrayRange) Count() int {
+	n := 0
+	for _, iv := range r.Intervals {
+		c := iv.count()
+		if c > math.MaxInt-n {
+			return math.MaxInt
+		}
+		n += c
+	}
+	return n
+}
+
+// Indices returns each index of r, in ascending order, so it should only be
+// used for ranges whose Count is known to be reasonable
+func (r ArrayRange) Indices() []int {
+	indices := make([]int, 0, r.Count())
+	for _, iv := range r.Intervals {
+		if iv.Last < iv.First {
+			continue
+		}
+		// Stop before i passes Last, as it could overflow
+		for i := iv.First; ; i += iv.step() {
+			indices = append(indices, i)
+			if i > iv.Last-iv.step() {
+				break
+			}
+		}
+	}
+	return indices
+}
+
+// String formats r as a list of ranges, e.g. "1-5,8,10-20:2%2"
+func (r ArrayRange) String() string {
+	parts := make([]string, len(r.Intervals))
+	for i, iv := range r.Intervals {
+		parts[i] = strconv.Itoa(iv.First)
+		if iv.Last > iv.First {
+			parts[i] += "-" + strconv.Itoa(iv.Last)
+		}
+		if iv.Step > 1 {
+			parts[i] += ":" + strconv.Itoa(iv.Step)
+		}
+	}
+	s := strings.Join(parts, ",")
+	if r.Limit > 0 {
+		s += "%" + strconv.Itoa(r.Limit)
+	}
+	return s
+}
+
+// hasSteps reports whether r has intervals with steps, which TORQUE doesn't
+// accept
+func (r ArrayRange) hasSteps() bool {
+	for _, iv := range r.Intervals {
+		if iv.step() > 1 && iv.Last > iv.First {
+			return true
+		}
+	}
+	return false
+}
+
+// serverString formats r as TORQUE accepts it, with the indices of the
+// intervals with steps listed, e.g. "0,2,4,10-12" for "0-4:2,10-12". r's
+// Count should be known to be reasonable.
+func (r ArrayRange) serverString() string {
+	if !r.hasSteps() {
+		return r.String()
+	}
+	var parts []string
+	for _, iv := range r.Intervals {
+		if iv.step() > 1 {
+			for _, i := range (ArrayRange{Intervals: []ArrayInterval{iv}}).Indices() {
+				parts = append(parts, strconv.Itoa(i))
+			}
+		} else {
+			parts = append(parts, ArrayRange{Intervals: []ArrayInterval{iv}}.String())
+		}
+	}
+	s := strings.Join(parts, ",")
+	if r.Limit > 0 {
+		s += "%" + strconv.Itoa(r.Limit)
+	}
+	return s
+}
+
+// Contains reports whether index is in r
+func (r ArrayRange) Contains(index int) bool {
+	i := sort.Search(len(r.Intervals), func(i int) bool {
+		return r.Intervals[i].Last >= index
+	})
+	if i == len(r.Intervals) {
+		return false
+	}
+	return r.Intervals[i].contains(index)
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (r ArrayRange) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (r *ArrayRange) UnmarshalText(text []byte) error {
+	v, err := ParseArrayRange(string(text))
+	if err != nil {
+		return err
+	}
+	*r = v
+	return nil
+}
+
+// SubmitArray submits script as a job array with the indices of r, and
+// returns the ID of the array, e.g. "1234[].server"
+func SubmitArray(c Client, attribs []Attrib, script string, destination string, r ArrayRange) (JobID, error) {
+	if len(r.Intervals) == 0 {
+		return JobID{}, errors.New("pbs: empty array range")
+	}
+	if r.Count() > MaxArraySize {
+		return JobID{}, ErrArrayTooLarge
+	}
+	attribs = append(attribs[:len(attribs):len(attribs)], Attrib{Name: ATTR_t, Value: r.serverString()})
+	id, err := c.Submit(attribs, script, destination, "")
+	if err != nil {
+		return JobID{}, err
+	}
+	return ParseJobID(id)
+}
+
+// StatArray returns the status of each subjob of the array id
+func StatArray(c Client, id JobID) ([]Job, error) {
+	jobs, err := StatJobs(c, id.Parent().String(), ExtendSubjobs)
+	if err != nil {
+		return nil, err
+	}
+	subjobs := jobs[:0]
+	for _, job := range jobs {
+		if sub, err := ParseJobID(job.ID); err == nil && sub.Seq == id.Seq && sub.Array && sub.ArrayIndex >= 0 {
+			subjobs = append(subjobs, job)
+		}
+	}
+	return subjobs, nil
+}
+
+// ArraySummary returns the number of subjobs of the array id in each state
+func ArraySummary(c Client, id JobID) (StateCount, error) {
+	jobs, err := StatArray(c, id)
+	if err != nil {
+		return StateCount{}, err
+	}
+	var count StateCount
+	for _, job := range jobs {
+		count.add(job.State)
+	}
+	return count, nil
+}
+
+// arrayExtend returns the extend for an operation on the subjobs r of an
+// array, or on all of them if r is empty
+func arrayExtend(r ArrayRange) (string, error) {
+	if len(r.Intervals) == 0 {
+		return "", nil
+	}
+	if r.Count() > MaxArraySize {
+		return "", ErrArrayTooLarge
+	}
+	return arrayRangeExtend + ArrayRange{Intervals: r.Intervals}.serverString(), nil
+}
+
+// HoldArray holds the subjobs r of the array id, all of them if r is empty
+func HoldArray(c Client, id JobID, r ArrayRange, holdType Hold) error {
+	extend, err := arrayExtend(r)
+	if err != nil {
+		return err
+	}
+	return c.HoldJob(id.Parent().String(), holdType, extend)
+}
+
+// RlsArray releases the subjobs r of the array id, all of them if r is empty
+func RlsArray(c Client, id JobID, r ArrayRange, holdType Hold) error {
+	extend, err := arrayExtend(r)
+	if err != nil {
+		return err
+	}
+	return c.RlsJob(id.Parent().String(), holdType, extend)
+}
+
+// DelArray deletes the subjobs r of the array id, all of them if r is empty
+func DelArray(c Client, id JobID, r ArrayRange) error {
+	extend, err := arrayExtend(r)
+	if err != nil {
+		return err
+	}
+	return c.DelJob(id.Parent().String(), extend)
+}
+
+// AlterArray alters the subjobs r of the array id, all of them if r is
+// empty
+func AlterArray(c Client, id JobID, r ArrayRange, attribs []Attrib) error {
+	extend, err := arrayExtend(r)
+	if err != nil {
+		return err
+	}
+	return c.AlterJob(id.Parent().String(), attribs, extend)
+}
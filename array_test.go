@@ -0,0 +1,190 @@
+package pbs
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestArrayRange(t *testing.T) {
+	tests := []struct {
+		s       string
+		indices []int
+		limit   int
+		str     string
+	}{
+		{"1-5", []int{1, 2, 3, 4, 5}, 0, "1-5"},
+		{"1-4%2", []int{1, 2, 3, 4}, 2, "1-4%2"},
+		{"0,4,10-12", []int{0, 4, 10, 11, 12}, 0, "0,4,10-12"},
+		{"5,1-3,2", []int{1, 2, 3, 5}, 0, "1-3,5"},
+		{"7", []int{7}, 0, "7"},
+		{"1-3,4,6", []int{1, 2, 3, 4, 6}, 0, "1-4,6"},
+		{"0-10:3,20", []int{0, 3, 6, 9, 20}, 0, "0-9:3,20"},
+		{"0-8:4,4,9-9:2%1", []int{0, 4, 8, 9}, 1, "0-8:4,9%1"},
+	}
+	for _, test := range tests {
+		r, err := ParseArrayRange(test.s)
+		if err != nil || !reflect.DeepEqual(r.Indices(), test.indices) || r.Count() != len(test.indices) || r.Limit != test.limit {
+			t.Errorf("ParseArrayRange(%q) returned %+v, %v\n", test.s, r, err)
+		}
+		if r.String() != test.str {
+			t.Errorf("%q formatted as %q, expected %q\n", test.s, r.String(), test.str)
+		}
+	}
+	for _, bad := range []string{"", "1-", "-1", "5-1", "1,,2", "1-5%", "1-5%0", "a", "5:2", "1-5:0", "0-10:2,1-3"} {
+		if _, err := ParseArrayRange(bad); err == nil {
+			t.Errorf("ParseArrayRange(%q) didn't fail\n", bad)
+		}
+	}
+	for _, large := range []string{"0-20000000", "0-2147483647", "0-100000,200000-300000"} {
+		if _, err := ParseArrayRange(large); !errors.Is(err, ErrArrayTooLarge) {
+			t.Errorf("ParseArrayRange(%q) returned %v\n", large, err)
+		}
+	}
+	if r := Range(3, 5); r.String() != "3-5" || !r.Contains(4) || r.Contains(6) || r.Contains(2) {
+		t.Errorf("Range(3, 5) returned %s\n", r)
+	}
+	if r := Range(1, math.MaxInt); r.Count() != math.MaxInt || !r.Contains(math.MaxInt) || r.Contains(0) {
+		t.Errorf("Range(1, MaxInt) returned %s with %d indices\n", r, r.Count())
+	}
+	if r := Range(math.MinInt, math.MaxInt); r.Count() != math.MaxInt {
+		t.Errorf("Range(MinInt, MaxInt) has %d indices\n", r.Count())
+	}
+	built := ArrayRange{Intervals: []ArrayInterval{{First: 1, Last: 5}, {First: 10, Last: 15, Step: 2}, {First: 20, Last: 19}}}
+	if built.Count() != 8 || !built.Contains(3) || !built.Contains(14) || built.Contains(15) || built.Contains(20) {
+		t.Errorf("ArrayRange %+v has %d indices\n", built, built.Count())
+	}
+	if indices := built.Indices(); !reflect.DeepEqual(indices, []int{1, 2, 3, 4, 5, 10, 12, 14}) {
+		t.Errorf("ArrayRange %+v has indices %v\n", built, indices)
+	}
+	if _, err := SubmitArray(connectFake(t, NewFakeServer("fake")), nil, "test.sh", "", ArrayRange{Intervals: []ArrayInterval{{First: 1, Last: 5}}}); err != nil {
+		t.Errorf("SubmitArray of a range without steps failed: %s\n", err)
+	}
+	if _, err := SubmitArray(connectFake(t, NewFakeServer("fake")), nil, "test.sh", "", Range(0, math.MaxInt)); !errors.Is(err, ErrArrayTooLarge) {
+		t.Errorf("SubmitArray of a huge range returned %v\n", err)
+	}
+}
+
+func TestSubmitArray(t *testing.T) {
+	server := NewFakeServer("fake")
+	client := connectFake(t, server)
+
+	r, _ := ParseArrayRange("1-4%2")
+	id, err := SubmitArray(client, []Attrib{{Name: ATTR_N, Value: "sweep"}}, "test.sh", "", r)
+	if err != nil {
+		t.Fatalf("SubmitArray failed: %s\n", err)
+	}
+	if !id.IsArrayParent() || id.String() != "1[].fake" {
+		t.Errorf("SubmitArray returned %s\n", id)
+	}
+	if v := jobAttribute(t, client, id.String(), ATTR_t); v != "1-4%2" {
+		t.Errorf("%s has %s of %q\n", id, ATTR_t, v)
+	}
+
+	// The slot limit keeps two of the four subjobs queued
+	server.Advance(0)
+	subjobs, err := StatArray(client, id)
+	if err != nil || len(subjobs) != 4 {
+		t.Fatalf("StatArray returned %d jobs, %v\n", len(subjobs), err)
+	}
+	if subjobs[2].ID != "1[3].fake" || subjobs[2].Name != "sweep-3" || subjobs[2].Extra[ATTR_array_id] != "3" {
+		t.Errorf("unexpected subjob %+v\n", subjobs[2])
+	}
+	count, err := ArraySummary(client, id)
+	if err != nil || count != (StateCount{Queued: 2, Running: 2}) {
+		t.Errorf("ArraySummary returned %+v, %v\n", count, err)
+	}
+	if state := jobAttribute(t, client, id.String(), ATTR_state); state != "R" {
+		t.Errorf("array is in state %s, expected R\n", state)
+	}
+
+	// Without the extend the array is reported rather than its subjobs
+	jobs, err := StatJobs(client, "", "")
+	if err != nil || len(jobs) != 1 || jobs[0].ID != "1[].fake" {
+		t.Errorf("StatJobs returned %+v, %v\n", jobs, err)
+	}
+
+	server.Advance(time.Minute)
+	count, _ = ArraySummary(client, id)
+	if count != (StateCount{Running: 2, Complete: 2}) {
+		t.Errorf("after a minute the array has %+v\n", count)
+	}
+}
+
+func TestSubmitArraySteps(t *testing.T) {
+	server := NewFakeServer("fake")
+	client := connectFake(t, server)
+
+	// TORQUE doesn't take steps, so their indices are listed
+	r, _ := ParseArrayRange("0-8:4,10-11%2")
+	id, err := SubmitArray(client, nil, "test.sh", "", r)
+	if err != nil {
+		t.Fatalf("SubmitArray failed: %s\n", err)
+	}
+	if v := jobAttribute(t, client, id.String(), ATTR_t); v != "0,4,8,10-11%2" {
+		t.Errorf("%s has %s of %q\n", id, ATTR_t, v)
+	}
+
+	if _, err := client.Submit([]Attrib{{Name: ATTR_t, Value: "0-8:4"}}, "test.sh", "", ""); !errors.Is(err, ErrBadAttrValue) {
+		t.Errorf("Submit of an array with steps returned %v\n", err)
+	}
+	if _, err := ParseQsubArgs([]string{"-t", "0-8:4"}); err == nil {
+		t.Errorf("qsub -t with steps didn't fail\n")
+	}
+}
+
+func TestArraySubset(t *testing.T) {
+	server := NewFakeServer("fake")
+	client := connectFake(t, server)
+	client.Manager(MGR_CMD_SET, MGR_OBJ_SERVER, "", []Attrib{{Name: "scheduling", Value: "False"}}, "")
+
+	id, err := SubmitArray(client, nil, "test.sh", "", Range(0, 5))
+	if err != nil {
+		t.Fatalf("SubmitArray failed: %s\n", err)
+	}
+
+	even, _ := ParseArrayRange("0-4:2")
+	if err := HoldArray(client, id, even, USER_HOLD); err != nil {
+		t.Errorf("HoldArray failed: %s\n", err)
+	}
+	if err := AlterArray(client, id, even, []Attrib{{Name: ATTR_l, Resource: "walltime", Value: "01:00:00"}}); err != nil {
+		t.Errorf("AlterArray failed: %s\n", err)
+	}
+	if err := DelArray(client, id, Range(5, 5)); err != nil {
+		t.Errorf("DelArray failed: %s\n", err)
+	}
+	if err := DelArray(client, id, Range(0, math.MaxInt)); !errors.Is(err, ErrArrayTooLarge) {
+		t.Errorf("DelArray of a huge range returned %v\n", err)
+	}
+
+	subjobs, err := StatArray(client, id)
+	if err != nil || len(subjobs) != 5 {
+		t.Fatalf("StatArray returned %d jobs, %v\n", len(subjobs), err)
+	}
+	for _, job := range subjobs {
+		sub, _ := ParseJobID(job.ID)
+		held := sub.ArrayIndex%2 == 0
+		if (job.State == StateHeld) != held || (job.Resources["walltime"] == "01:00:00") != held {
+			t.Errorf("subjob %s is %s with walltime %q\n", job.ID, job.State, job.Resources["walltime"])
+		}
+	}
+
+	if err := RlsArray(client, id, ArrayRange{}, USER_HOLD); err != nil {
+		t.Errorf("RlsArray failed: %s\n", err)
+	}
+	if count, _ := ArraySummary(client, id); count != (StateCount{Queued: 5}) {
+		t.Errorf("after release the array has %+v\n", count)
+	}
+
+	if err := DelArray(client, id, Range(10, 12)); !errors.Is(err, ErrUnknownJob) {
+		t.Errorf("DelArray of missing indices returned %v\n", err)
+	}
+	if err := DelArray(client, id, ArrayRange{}); err != nil {
+		t.Errorf("DelArray of the whole array failed: %s\n", err)
+	}
+	if _, err := StatArray(client, id); !errors.Is(err, ErrUnknownJob) {
+		t.Errorf("StatArray of a deleted array returned %v\n", err)
+	}
+}
@@ -0,0 +1,31 @@
+package pbs
+
+// Client is a connection to a TORQUE server. Each method corresponds to the
+// Pbs_* function of the same name, without the connection handle argument.
+//
+// Code written against Client rather than the Pbs_* functions can be run
+// against any Backend, e.g. libtorque in production and an in-process fake
+// in tests.
+type Client interface {
+	Submit(attribs []Attrib, script string, destination string, extend string) (string, error)
+	StatJob(id string, attribs []Attrib, extend string) ([]BatchStatus, error)
+	StatNode(id string, attribs []Attrib, extend string) ([]BatchStatus, error)
+	StatQue(id string, attribs []Attrib, extend string) ([]BatchStatus, error)
+	StatServer(attribs []Attrib, extend string) ([]BatchStatus, error)
+	SelectJob(attribs []Attrib, extend string) ([]string, error)
+	SelStat(attribs []Attrib, extend string) ([]BatchStatus, error)
+	HoldJob(id string, holdType Hold, extend string) error
+	RlsJob(id string, holdType Hold, extend string) error
+	DelJob(id string, extend string) error
+	AlterJob(id string, attribs []Attrib, extend string) error
+	MoveJob(id string, destination string, extend string) error
+	SigJob(id string, signal string, extend string) error
+	Manager(command Command, objType ObjectType, name string, attribs []Attrib, extend string) error
+	Disconnect() error
+}
+
+// Backend creates Clients. server has the same meaning as for Pbs_connect,
+// an empty string selects the default server.
+type Backend interface {
+	Connect(server string) (Client, error)
+}
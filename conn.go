@@ -0,0 +1,217 @@
+package pbs
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrClosed is returned, wrapped in a *PBSError, when a Conn is used after
+// Close
+var ErrClosed = errors.New("pbs: use of closed connection")
+
+// Conn is a connection to a server. Its methods mirror the Pbs_* functions,
+// without the handle, and fail with ErrClosed once the connection has been
+// closed. Conn is a Client, so it can be used wherever one is expected.
+type Conn struct {
+	mu     sync.Mutex
+	client Client
+	server string
+	closed bool
+}
+
+var _ Client = (*Conn)(nil)
+
+// Connect connects to server with DefaultBackend. As with Pbs_connect an
+// empty server selects the default server.
+func Connect(server string) (*Conn, error) {
+	return ConnectWith(DefaultBackend, server)
+}
+
+// ConnectWith connects to server with backend
+func ConnectWith(backend Backend, server string) (*Conn, error) {
+	if server == "" {
+		if d, ok := backend.(interface{ DefaultServer() string }); ok {
+			server = d.DefaultServer()
+		}
+	}
+
+	client, err := backend.Connect(server)
+	if err != nil {
+		return nil, err
+	}
+	return &Conn{client: client, server: server}, nil
+}
+
+// Server returns the name of the server connected to
+func (c *Conn) Server() string {
+	return c.server
+}
+
+// Close disconnects from the server. Closing a closed Conn returns an error
+// wrapping ErrClosed.
+func (c *Conn) Close() error {
+	if !c.markClosed() {
+		return closedError("pbs_disconnect", "")
+	}
+	return c.client.Disconnect()
+}
+
+// Disconnect is Close, so that Conn is a Client
+func (c *Conn) Disconnect() error {
+	return c.Close()
+}
+
+func closedError(op string, id string) error {
+	return &PBSError{Op: op, ID: id, Errno: PBSE_NOCONNECTS, Msg: errorText[PBSE_NOCONNECTS], Err: ErrClosed}
+}
+
+// markClosed marks c as closed, reporting whether it was open
+func (c *Conn) markClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	wasOpen := !c.closed
+	c.closed = true
+	return wasOpen
+}
+
+func (c *Conn) isClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+// get returns the Client for an operation, or the error if c is closed
+func (c *Conn) get(op string, id string) (Client, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil, closedError(op, id)
+	}
+	return c.client, nil
+}
+
+// Submit submits the job script to the server, see Pbs_submit
+func (c *Conn) Submit(attribs []Attrib, script string, destination string, extend string) (string, error) {
+	client, err := c.get("pbs_submit", script)
+	if err != nil {
+		return "", err
+	}
+	return client.Submit(attribs, script, destination, extend)
+}
+
+// SubmitScript submits a job with the script's contents, see SubmitBytes
+func (c *Conn) SubmitScript(attribs []Attrib, script []byte, destination string, extend string) (string, error) {
+	client, err := c.get("pbs_submit", "")
+	if err != nil {
+		return "", err
+	}
+	return SubmitBytes(client, attribs, script, destination, extend)
+}
+
+func (c *Conn) StatJob(id string, attribs []Attrib, extend string) ([]BatchStatus, error) {
+	client, err := c.get("pbs_statjob", id)
+	if err != nil {
+		return nil, err
+	}
+	return client.StatJob(id, attribs, extend)
+}
+
+func (c *Conn) StatNode(id string, attribs []Attrib, extend string) ([]BatchStatus, error) {
+	client, err := c.get("pbs_statnode", id)
+	if err != nil {
+		return nil, err
+	}
+	return client.StatNode(id, attribs, extend)
+}
+
+func (c *Conn) StatQue(id string, attribs []Attrib, extend string) ([]BatchStatus, error) {
+	client, err := c.get("pbs_statque", id)
+	if err != nil {
+		return nil, err
+	}
+	return client.StatQue(id, attribs, extend)
+}
+
+func (c *Conn) StatServer(attribs []Attrib, extend string) ([]BatchStatus, error) {
+	client, err := c.get("pbs_statserver", "")
+	if err != nil {
+		return nil, err
+	}
+	return client.StatServer(attribs, extend)
+}
+
+// SelectJob returns the IDs of the jobs matching attribs
+func (c *Conn) SelectJob(attribs []Attrib, extend string) ([]string, error) {
+	client, err := c.get("pbs_selectjob", "")
+	if err != nil {
+		return nil, err
+	}
+	return client.SelectJob(attribs, extend)
+}
+
+func (c *Conn) SelStat(attribs []Attrib, extend string) ([]BatchStatus, error) {
+	client, err := c.get("pbs_selstat", "")
+	if err != nil {
+		return nil, err
+	}
+	return client.SelStat(attribs, extend)
+}
+
+func (c *Conn) HoldJob(id string, holdType Hold, extend string) error {
+	client, err := c.get("pbs_holdjob", id)
+	if err != nil {
+		return err
+	}
+	return client.HoldJob(id, holdType, extend)
+}
+
+func (c *Conn) RlsJob(id string, holdType Hold, extend string) error {
+	client, err := c.get("pbs_rlsjob", id)
+	if err != nil {
+		return err
+	}
+	return client.RlsJob(id, holdType, extend)
+}
+
+// DelJob deletes a job on the server
+func (c *Conn) DelJob(id string, extend string) error {
+	client, err := c.get("pbs_deljob", id)
+	if err != nil {
+		return err
+	}
+	return client.DelJob(id, extend)
+}
+
+func (c *Conn) AlterJob(id string, attribs []Attrib, extend string) error {
+	client, err := c.get("pbs_alterjob", id)
+	if err != nil {
+		return err
+	}
+	return client.AlterJob(id, attribs, extend)
+}
+
+func (c *Conn) MoveJob(id string, destination string, extend string) error {
+	client, err := c.get("pbs_movejob", id)
+	if err != nil {
+		return err
+	}
+	return client.MoveJob(id, destination, extend)
+}
+
+func (c *Conn) SigJob(id string, signal string, extend string) error {
+	client, err := c.get("pbs_sigjob", id)
+	if err != nil {
+		return err
+	}
+	return client.SigJob(id, signal, extend)
+}
+
+func (c *Conn) Manager(command Command, objType ObjectType, name string, attribs []Attrib, extend string) error {
+	client, err := c.get("pbs_manager", name)
+	if err != nil {
+		return err
+	}
+	return client.Manager(command, objType, name, attribs, extend)
+}
@@ -0,0 +1,50 @@
+package pbs
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestConn(t *testing.T) {
+	s := NewFakeServer("fake")
+	conn, err := ConnectWith(s, "")
+	if err != nil {
+		t.Fatalf("Connect failed: %s\n", err)
+	}
+	if conn.Server() != "fake" {
+		t.Errorf("Conn.Server() is %q\n", conn.Server())
+	}
+
+	id, err := conn.Submit([]Attrib{{Name: ATTR_N, Value: "conn"}}, "job.sh", "", "")
+	if err != nil {
+		t.Fatalf("Submit failed: %s\n", err)
+	}
+	if name := jobAttribute(t, conn, id, ATTR_N); name != "conn" {
+		t.Errorf("Job_Name is %s\n", name)
+	}
+
+	if err := conn.Close(); err != nil {
+		t.Errorf("Close failed: %s\n", err)
+	}
+
+	_, err = conn.StatJob(id, nil, "")
+	if !errors.Is(err, ErrClosed) {
+		t.Errorf("StatJob after Close returned %v\n", err)
+	}
+	var pe *PBSError
+	if !errors.As(err, &pe) || pe.Op != "pbs_statjob" || pe.ID != id {
+		t.Errorf("StatJob after Close returned %#v\n", err)
+	}
+	if err := conn.DelJob(id, ""); !errors.Is(err, ErrClosed) {
+		t.Errorf("DelJob after Close returned %v\n", err)
+	}
+	if err := conn.Close(); !errors.Is(err, ErrClosed) {
+		t.Errorf("Second Close returned %v\n", err)
+	}
+}
+
+func TestConnectWithError(t *testing.T) {
+	if _, err := ConnectWith(NewFakeServer("fake"), "other"); !errors.Is(err, ErrNoServer) {
+		t.Errorf("Connecting to the wrong server returned %v\n", err)
+	}
+}
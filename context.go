@@ -0,0 +1,198 @@
+package pbs
+
+import "context"
+
+// The Context variants of the Conn methods stop waiting for the server when
+// ctx is done, returning a *PBSError wrapping ctx.Err(). The Conn is then
+// closed, as the state of the abandoned request is unknown: connections
+// which support it are torn down at once, others, such as libtorque
+// handles, are disconnected as soon as the call returns.
+
+// aborter is implemented by Clients whose requests in progress can be
+// interrupted
+type aborter interface {
+	abort()
+}
+
+func contextError(op string, id string, err error) error {
+	return &PBSError{Op: op, ID: id, Msg: "request abandoned", Err: err}
+}
+
+// ConnectContext is Connect, giving up when ctx is done
+func ConnectContext(ctx context.Context, server string) (*Conn, error) {
+	return ConnectWithContext(ctx, DefaultBackend, server)
+}
+
+// ConnectWithContext is ConnectWith, giving up when ctx is done. A
+// connection made after that is disconnected.
+func ConnectWithContext(ctx context.Context, backend Backend, server string) (*Conn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, contextError("pbs_connect", server, err)
+	}
+
+	type result struct {
+		conn *Conn
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		conn, err := ConnectWith(backend, server)
+		done <- result{conn, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.conn, r.err
+	case <-ctx.Done():
+		go func() {
+			if r := <-done; r.err == nil {
+				r.conn.Close()
+			}
+		}()
+		return nil, contextError("pbs_connect", server, ctx.Err())
+	}
+}
+
+// withContext calls f with c's Client, giving up when ctx is done
+func withContext[T any](ctx context.Context, c *Conn, op string, id string, f func(Client) (T, error)) (T, error) {
+	var zero T
+	client, err := c.get(op, id)
+	if err != nil {
+		return zero, err
+	}
+	if err := ctx.Err(); err != nil {
+		return zero, contextError(op, id, err)
+	}
+
+	type result struct {
+		v   T
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		v, err := f(client)
+		done <- result{v, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.v, r.err
+	case <-ctx.Done():
+		if c.markClosed() {
+			if a, ok := client.(aborter); ok {
+				a.abort()
+			}
+			go func() {
+				<-done
+				client.Disconnect()
+			}()
+		}
+		return zero, contextError(op, id, ctx.Err())
+	}
+}
+
+// SubmitContext is Submit, giving up when ctx is done. The job may still
+// have been created on the server.
+func (c *Conn) SubmitContext(ctx context.Context, attribs []Attrib, script string, destination string, extend string) (string, error) {
+	return withContext(ctx, c, "pbs_submit", script, func(client Client) (string, error) {
+		return client.Submit(attribs, script, destination, extend)
+	})
+}
+
+// StatJobContext is StatJob, giving up when ctx is done
+func (c *Conn) StatJobContext(ctx context.Context, id string, attribs []Attrib, extend string) ([]BatchStatus, error) {
+	return withContext(ctx, c, "pbs_statjob", id, func(client Client) ([]BatchStatus, error) {
+		return client.StatJob(id, attribs, extend)
+	})
+}
+
+// StatNodeContext is StatNode, giving up when ctx is done
+func (c *Conn) StatNodeContext(ctx context.Context, id string, attribs []Attrib, extend string) ([]BatchStatus, error) {
+	return withContext(ctx, c, "pbs_statnode", id, func(client Client) ([]BatchStatus, error) {
+		return client.StatNode(id, attribs, extend)
+	})
+}
+
+// StatQueContext is StatQue, giving up when ctx is done
+func (c *Conn) StatQueContext(ctx context.Context, id string, attribs []Attrib, extend string) ([]BatchStatus, error) {
+	return withContext(ctx, c, "pbs_statque", id, func(client Client) ([]BatchStatus, error) {
+		return client.StatQue(id, attribs, extend)
+	})
+}
+
+// StatServerContext is StatServer, giving up when ctx is done
+func (c *Conn) StatServerContext(ctx context.Context, attribs []Attrib, extend string) ([]BatchStatus, error) {
+	return withContext(ctx, c, "pbs_statserver", "", func(client Client) ([]BatchStatus, error) {
+		return client.StatServer(attribs, extend)
+	})
+}
+
+// SelectJobContext is SelectJob, giving up when ctx is done
+func (c *Conn) SelectJobContext(ctx context.Context, attribs []Attrib, extend string) ([]string, error) {
+	return withContext(ctx, c, "pbs_selectjob", "", func(client Client) ([]string, error) {
+		return client.SelectJob(attribs, extend)
+	})
+}
+
+// SelStatContext is SelStat, giving up when ctx is done
+func (c *Conn) SelStatContext(ctx context.Context, attribs []Attrib, extend string) ([]BatchStatus, error) {
+	return withContext(ctx, c, "pbs_selstat", "", func(client Client) ([]BatchStatus, error) {
+		return client.SelStat(attribs, extend)
+	})
+}
+
+// HoldJobContext is HoldJob, giving up when ctx is done
+func (c *Conn) HoldJobContext(ctx context.Context, id string, holdType Hold, extend string) error {
+	_, err := withContext(ctx, c, "pbs_holdjob", id, func(client Client) (struct{}, error) {
+		return struct{}{}, client.HoldJob(id, holdType, extend)
+	})
+	return err
+}
+
+// RlsJobContext is RlsJob, giving up when ctx is done
+func (c *Conn) RlsJobContext(ctx context.Context, id string, holdType Hold, extend string) error {
+	_, err := withContext(ctx, c, "pbs_rlsjob", id, func(client Client) (struct{}, error) {
+		return struct{}{}, client.RlsJob(id, holdType, extend)
+	})
+	return err
+}
+
+// DelJobContext is DelJob, giving up when ctx is done
+func (c *Conn) DelJobContext(ctx context.Context, id string, extend string) error {
+	_, err := withContext(ctx, c, "pbs_deljob", id, func(client Client) (struct{}, error) {
+		return struct{}{}, client.DelJob(id, extend)
+	})
+	return err
+}
+
+// AlterJobContext is AlterJob, giving up when ctx is done
+func (c *Conn) AlterJobContext(ctx context.Context, id string, attribs []Attrib, extend string) error {
+	_, err := withContext(ctx, c, "pbs_alterjob", id, func(client Client) (struct{}, error) {
+		return struct{}{}, client.AlterJob(id, attribs, extend)
+	})
+	return err
+}
+
+// MoveJobContext is MoveJob, giving up when ctx is done
+func (c *Conn) MoveJobContext(ctx context.Context, id string, destination string, extend string) error {
+	_, err := withContext(ctx, c, "pbs_movejob", id, func(client Client) (struct{}, error) {
+		return struct{}{}, client.MoveJob(id, destination, extend)
+	})
+	return err
+}
+
+// SigJobContext is SigJob, giving up when ctx is done
+func (c *Conn) SigJobContext(ctx context.Context, id string, signal string, extend string) error {
+	_, err := withContext(ctx, c, "pbs_sigjob", id, func(client Client) (struct{}, error) {
+		return struct{}{}, client.SigJob(id, signal, extend)
+	})
+	return err
+}
+
+// ManagerContext is Manager, giving up when ctx is done
+func (c *Conn) ManagerContext(ctx context.Context, command Command, objType ObjectType, name string, attribs []Attrib, extend string) error {
+	_, err := withContext(ctx, c, "pbs_manager", name, func(client Client) (struct{}, error) {
+		return struct{}{}, client.Manager(command, objType, name, attribs, extend)
+	})
+	return err
+}
@@ -0,0 +1,119 @@
+package pbs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// silentServer accepts connections and reads requests without replying,
+// like a hung pbs_server. Each connection is sent on closed once the client
+// has closed it.
+func silentServer(t *testing.T) (string, <-chan struct{}) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Couldn't listen: %s\n", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	closed := make(chan struct{}, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				io.Copy(io.Discard, conn)
+				conn.Close()
+				closed <- struct{}{}
+			}()
+		}
+	}()
+	return listener.Addr().String(), closed
+}
+
+func TestContextDeadline(t *testing.T) {
+	addr, closed := silentServer(t)
+	backend := DIS{Authenticate: func(net.Conn, string, int) error { return nil }}
+	conn, err := ConnectWith(backend, addr)
+	if err != nil {
+		t.Fatalf("Connect failed: %s\n", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = conn.StatJobContext(ctx, "1.server", nil, "")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("StatJobContext on a hung server returned %v\n", err)
+	}
+	var pe *PBSError
+	if !errors.As(err, &pe) || pe.Op != "pbs_statjob" {
+		t.Errorf("StatJobContext returned %#v\n", err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("StatJobContext took %s to time out\n", d)
+	}
+
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Errorf("Connection wasn't closed after the deadline\n")
+	}
+	if _, err := conn.StatServer(nil, ""); !errors.Is(err, ErrClosed) {
+		t.Errorf("StatServer after abandoned request returned %v\n", err)
+	}
+}
+
+func TestContextCancelled(t *testing.T) {
+	conn, err := ConnectWith(NewFakeServer("fake"), "")
+	if err != nil {
+		t.Fatalf("Connect failed: %s\n", err)
+	}
+	defer conn.Close()
+
+	id, err := conn.SubmitContext(context.Background(), nil, "job.sh", "", "")
+	if err != nil {
+		t.Fatalf("SubmitContext failed: %s\n", err)
+	}
+	if err := conn.HoldJobContext(context.Background(), id, USER_HOLD, ""); err != nil {
+		t.Errorf("HoldJobContext failed: %s\n", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := conn.DelJobContext(ctx, id, ""); !errors.Is(err, context.Canceled) {
+		t.Errorf("DelJobContext with a cancelled context returned %v\n", err)
+	}
+	if _, err := conn.StatJob(id, nil, ""); err != nil {
+		t.Errorf("Conn unusable after a request wasn't made: %s\n", err)
+	}
+}
+
+func TestConnectContext(t *testing.T) {
+	addr, closed := silentServer(t)
+	unblock := make(chan struct{})
+	defer close(unblock)
+	backend := DIS{Authenticate: func(net.Conn, string, int) error {
+		<-unblock
+		return nil
+	}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := ConnectWithContext(ctx, backend, addr); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("ConnectWithContext returned %v\n", err)
+	}
+
+	// The connection made once authentication finishes is closed
+	unblock <- struct{}{}
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Errorf("Late connection wasn't closed\n")
+	}
+}
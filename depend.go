@@ -0,0 +1,227 @@
+package pbs
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// DependType is the type of a job dependency
+type DependType string
+
+// Dependency types. The after types hold a job until the jobs it depends on
+// have started (after) or finished, and the before types are their
+// converse. The array types depend on the subjobs of job arrays, given as
+// e.g. "12[].srv", or "12[][5].srv" for five of them. on and synccount take
+// a count rather than jobs.
+const (
+	DependAfter            DependType = "after"
+	DependAfterOK          DependType = "afterok"
+	DependAfterNotOK       DependType = "afternotok"
+	DependAfterAny         DependType = "afterany"
+	DependBefore           DependType = "before"
+	DependBeforeOK         DependType = "beforeok"
+	DependBeforeNotOK      DependType = "beforenotok"
+	DependBeforeAny        DependType = "beforeany"
+	DependAfterStartArray  DependType = "afterstartarray"
+	DependAfterOKArray     DependType = "afterokarray"
+	DependAfterNotOKArray  DependType = "afternotokarray"
+	DependAfterAnyArray    DependType = "afteranyarray"
+	DependBeforeStartArray DependType = "beforestartarray"
+	DependBeforeOKArray    DependType = "beforeokarray"
+	DependBeforeNotOKArray DependType = "beforenotokarray"
+	DependBeforeAnyArray   DependType = "beforeanyarray"
+	DependOn               DependType = "on"
+	DependSyncWith         DependType = "syncwith"
+	DependSyncCount        DependType = "synccount"
+)
+
+var dependTypes = map[DependType]bool{
+	DependAfter:            true,
+	DependAfterOK:          true,
+	DependAfterNotOK:       true,
+	DependAfterAny:         true,
+	DependBefore:           true,
+	DependBeforeOK:         true,
+	DependBeforeNotOK:      true,
+	DependBeforeAny:        true,
+	DependAfterStartArray:  true,
+	DependAfterOKArray:     true,
+	DependAfterNotOKArray:  true,
+	DependAfterAnyArray:    true,
+	DependBeforeStartArray: true,
+	DependBeforeOKArray:    true,
+	DependBeforeNotOKArray: true,
+	DependBeforeAnyArray:   true,
+	DependOn:               true,
+	DependSyncWith:         true,
+	DependSyncCount:        true,
+}
+
+// HasCount reports whether t takes a count rather than jobs
+func (t DependType) HasCount() bool {
+	return t == DependOn || t == DependSyncCount
+}
+
+// Dependency is one part of a depend attribute, e.g. "afterok:12.srv:13.srv"
+// or "on:2"
+type Dependency struct {
+	Type DependType
+	// Jobs are the IDs of the jobs depended on, as given or, from
+	// Pbs_statjob, with the server appended, e.g. "12.srv@srv"
+	Jobs []string
+	// Count is the count for on and synccount
+	Count int
+}
+
+// String formats d as it appears in the depend attribute
+func (d Dependency) String() string {
+	if d.Type.HasCount() {
+		return string(d.Type) + ":" + strconv.Itoa(d.Count)
+	}
+	return strings.Join(append([]string{string(d.Type)}, d.Jobs...), ":")
+}
+
+// Dependencies is the value of the depend attribute
+type Dependencies []Dependency
+
+// ParseDependencies parses a depend attribute
+func ParseDependencies(s string) (Dependencies, error) {
+	var deps Dependencies
+	for _, part := range strings.Split(s, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ":")
+		d := Dependency{Type: DependType(fields[0])}
+		if !dependTypes[d.Type] {
+			return nil, errors.New("pbs: unknown dependency type " + strconv.Quote(fields[0]))
+		}
+		if len(fields) < 2 {
+			return nil, errors.New("pbs: dependency " + strconv.Quote(part) + " has no jobs")
+		}
+
+		if d.Type.HasCount() {
+			n, err := strconv.Atoi(fields[1])
+			if err != nil || n < 0 || len(fields) > 2 {
+				return nil, errors.New("pbs: invalid dependency count " + strconv.Quote(part))
+			}
+			d.Count = n
+		} else {
+			for _, job := range fields[1:] {
+				if job == "" {
+					return nil, errors.New("pbs: empty job in dependency " + strconv.Quote(part))
+				}
+				d.Jobs = append(d.Jobs, job)
+			}
+		}
+		deps = append(deps, d)
+	}
+	return deps, nil
+}
+
+// String formats deps as the value of the depend attribute
+func (deps Dependencies) String() string {
+	parts := make([]string, len(deps))
+	for i, d := range deps {
+		parts[i] = d.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+// Attrib returns deps as the depend attribute, for Pbs_submit or
+// Pbs_alterjob
+func (deps Dependencies) Attrib() Attrib {
+	return Attrib{Name: ATTR_depend, Value: deps.String()}
+}
+
+// Add returns deps with jobs added to the dependency of type t, which is
+// added if it isn't there already. The types which take a count, on and
+// synccount, are set with SetCount instead, and deps is returned unchanged
+// for them.
+func (deps Dependencies) Add(t DependType, jobs ...string) Dependencies {
+	if t.HasCount() {
+		return deps
+	}
+	for i, d := range deps {
+		if d.Type == t {
+			deps = append(Dependencies(nil), deps...)
+			deps[i].Jobs = append(d.Jobs[:len(d.Jobs):len(d.Jobs)], jobs...)
+			return deps
+		}
+	}
+	return append(deps[:len(deps):len(deps)], Dependency{Type: t, Jobs: jobs})
+}
+
+// SetCount returns deps with the count of type t, on or synccount, set to n
+func (deps Dependencies) SetCount(t DependType, n int) Dependencies {
+	for i, d := range deps {
+		if d.Type == t {
+			deps = append(Dependencies(nil), deps...)
+			deps[i].Count = n
+			return deps
+		}
+	}
+	return append(deps[:len(deps):len(deps)], Dependency{Type: t, Count: n})
+}
+
+// Jobs returns the jobs of the dependency of type t
+func (deps Dependencies) Jobs(t DependType) []string {
+	var jobs []string
+	for _, d := range deps {
+		if d.Type == t {
+			jobs = append(jobs, d.Jobs...)
+		}
+	}
+	return jobs
+}
+
+// Replace returns deps with the job from replaced by to, e.g. after it's
+// been resubmitted. A job ID matches with or without the "@server" which
+// Pbs_statjob adds. If to is empty the job is removed, along with the
+// dependencies left without jobs.
+func (deps Dependencies) Replace(from, to string) Dependencies {
+	var result Dependencies
+	for _, d := range deps {
+		if d.Type.HasCount() {
+			result = append(result, d)
+			continue
+		}
+
+		var jobs []string
+		for _, job := range d.Jobs {
+			if id, _, _ := strings.Cut(job, "@"); id == from || job == from {
+				job = to
+			}
+			if job != "" {
+				jobs = append(jobs, job)
+			}
+		}
+		if jobs != nil {
+			result = append(result, Dependency{Type: d.Type, Jobs: jobs})
+		}
+	}
+	return result
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (deps Dependencies) MarshalText() ([]byte, error) {
+	return []byte(deps.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (deps *Dependencies) UnmarshalText(text []byte) error {
+	v, err := ParseDependencies(string(text))
+	if err != nil {
+		return err
+	}
+	*deps = v
+	return nil
+}
+
+// Dependencies parses the job's depend attribute, which is empty if the job
+// has no dependencies
+func (j Job) Dependencies() (Dependencies, error) {
+	s, ok := j.Extra[ATTR_depend]
+	if !ok || s == "" {
+		return nil, nil
+	}
+	return ParseDependencies(s)
+}
@@ -0,0 +1,88 @@
+package pbs
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDependencies(t *testing.T) {
+	s := "afterok:12.srv@srv:13.srv@srv,beforeany:20.srv,on:2,synccount:3"
+	deps, err := ParseDependencies(s)
+	if err != nil {
+		t.Fatalf("ParseDependencies failed: %s\n", err)
+	}
+	expected := Dependencies{
+		{Type: DependAfterOK, Jobs: []string{"12.srv@srv", "13.srv@srv"}},
+		{Type: DependBeforeAny, Jobs: []string{"20.srv"}},
+		{Type: DependOn, Count: 2},
+		{Type: DependSyncCount, Count: 3},
+	}
+	if !reflect.DeepEqual(deps, expected) {
+		t.Errorf("ParseDependencies returned %+v\n", deps)
+	}
+	if deps.String() != s {
+		t.Errorf("%q formatted as %q\n", s, deps.String())
+	}
+
+	arrays := "afterokarray:14[].srv,afterstartarray:15[][5].srv,beforeanyarray:16[].srv"
+	if deps, err := ParseDependencies(arrays); err != nil || deps.String() != arrays || deps[1].Jobs[0] != "15[][5].srv" {
+		t.Errorf("ParseDependencies(%q) returned %+v, %v\n", arrays, deps, err)
+	}
+
+	for _, bad := range []string{"", "afterok", "afterok:", "afterok:1:", "later:1", "on:x", "on:1:2", "afterok:1,,on:1"} {
+		if _, err := ParseDependencies(bad); err == nil {
+			t.Errorf("ParseDependencies(%q) didn't fail\n", bad)
+		}
+	}
+}
+
+func TestDependenciesBuild(t *testing.T) {
+	deps := Dependencies{}.Add(DependAfterOK, "1.srv").Add(DependAfterAny, "2.srv").Add(DependAfterOK, "3.srv").SetCount(DependOn, 1)
+	if a := deps.Attrib(); a != (Attrib{Name: ATTR_depend, Value: "afterok:1.srv:3.srv,afterany:2.srv,on:1"}) {
+		t.Errorf("Attrib returned %+v\n", a)
+	}
+	if jobs := deps.Jobs(DependAfterOK); !reflect.DeepEqual(jobs, []string{"1.srv", "3.srv"}) {
+		t.Errorf("Jobs(afterok) returned %v\n", jobs)
+	}
+
+	if got := deps.Add(DependOn, "4.srv"); !reflect.DeepEqual(got, deps) {
+		t.Errorf("Add of jobs to a count type returned %s\n", got)
+	}
+
+	replaced := deps.Replace("1.srv", "4.srv").Replace("2.srv", "")
+	if replaced.String() != "afterok:4.srv:3.srv,on:1" {
+		t.Errorf("Replace returned %s\n", replaced)
+	}
+	if deps.String() != "afterok:1.srv:3.srv,afterany:2.srv,on:1" {
+		t.Errorf("Replace modified the original to %s\n", deps)
+	}
+	if got := (Dependencies{{Type: DependAfter, Jobs: []string{"5.srv@srv"}}}).Replace("5.srv", "6.srv"); got.String() != "after:6.srv" {
+		t.Errorf("Replace of a job with a server returned %s\n", got)
+	}
+}
+
+func TestJobDependencies(t *testing.T) {
+	server := NewFakeServer("fake")
+	client := connectFake(t, server)
+
+	first, _ := client.Submit(nil, "first.sh", "", "")
+	deps := Dependencies{}.Add(DependAfterOK, first)
+	second, err := client.Submit([]Attrib{deps.Attrib()}, "second.sh", "", "")
+	if err != nil {
+		t.Fatalf("Submit failed: %s\n", err)
+	}
+
+	jobs, err := StatJobs(client, second, "")
+	if err != nil {
+		t.Fatalf("StatJobs failed: %s\n", err)
+	}
+	got, err := jobs[0].Dependencies()
+	if err != nil || !reflect.DeepEqual(got, deps) {
+		t.Errorf("Dependencies returned %+v, %v\n", got, err)
+	}
+
+	jobs, _ = StatJobs(client, first, "")
+	if got, err := jobs[0].Dependencies(); got != nil || err != nil {
+		t.Errorf("job without dependencies returned %+v, %v\n", got, err)
+	}
+}
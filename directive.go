@@ -0,0 +1,62 @@
+package pbs
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// DefaultDirectivePrefix starts the lines of a job script which hold qsub
+// options
+const DefaultDirectivePrefix = "#PBS"
+
+// ParseDirectives reads the directives at the start of a job script, the
+// lines starting with prefix, or DefaultDirectivePrefix if prefix is
+// empty, and returns the options they give, e.g.
+//
+//	#!/bin/sh
+//	#PBS -N analysis
+//	#PBS -l nodes=1:ppn=4,walltime=02:00:00
+//	#PBS -W depend=afterok:12.server
+//
+// As with qsub, the directives after the first line which isn't blank or
+// a comment are ignored. Unlike qsub, variables given to -v without a
+// value aren't looked up in the environment, and have an empty value.
+// Errors are *OptionError values with the line number of the directive.
+func ParseDirectives(r io.Reader, prefix string) (QsubOptions, error) {
+	if prefix == "" {
+		prefix = DefaultDirectivePrefix
+	}
+
+	var o QsubOptions
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		trimmed := strings.TrimSpace(text)
+		if trimmed == "" {
+			continue
+		}
+		if !strings.HasPrefix(trimmed, "#") && !strings.HasPrefix(text, prefix) {
+			break
+		}
+
+		rest := strings.TrimPrefix(text, prefix)
+		if len(rest) == len(text) || rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+			continue
+		}
+		args, err := splitWords(rest)
+		if err != nil {
+			return o, &OptionError{Line: line, Err: err}
+		}
+		args, err = o.parseArgs(args, line, nil)
+		if err != nil {
+			return o, err
+		}
+		if len(args) > 0 {
+			return o, &OptionError{Line: line, Err: errors.New("unexpected argument " + strconv.Quote(args[0]))}
+		}
+	}
+	return o, scanner.Err()
+}
@@ -0,0 +1,90 @@
+package pbs
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseDirectives(t *testing.T) {
+	script := `#!/bin/sh
+# a comment
+
+#PBS -N analysis -q long
+#PBS -l nodes=1:ppn=4,walltime=01:00:00
+#PBS -l walltime=02:00:00 -j oe -m abe -M "a@example.com,b@example.com"
+#PBS -W depend=afterok:12.srv,before:13.srv,umask=022
+#PBS -t 1-10%2 -v A=1 -v B=2 -V
+#PBS -A proj -p -10 -r n
+#PBS-N ignored
+#PBSX -N ignored
+  #PBS -N ignored
+echo hello
+#PBS -N ignored
+`
+	o, err := ParseDirectives(strings.NewReader(script), "")
+	if err != nil {
+		t.Fatalf("ParseDirectives failed: %s\n", err)
+	}
+	expected := QsubOptions{
+		Destination: "long",
+		ExportEnv:   true,
+		Attribs: []Attrib{
+			{Name: ATTR_N, Value: "analysis"},
+			{Name: ATTR_l, Resource: "nodes", Value: "1:ppn=4"},
+			{Name: ATTR_l, Resource: "walltime", Value: "02:00:00"},
+			{Name: ATTR_j, Value: "oe"},
+			{Name: ATTR_m, Value: "abe"},
+			{Name: ATTR_M, Value: "a@example.com,b@example.com"},
+			{Name: ATTR_depend, Value: "afterok:12.srv,before:13.srv"},
+			{Name: ATTR_umask, Value: "022"},
+			{Name: ATTR_t, Value: "1-10%2"},
+			{Name: ATTR_v, Value: "A=1,B=2"},
+			{Name: ATTR_A, Value: "proj"},
+			{Name: ATTR_p, Value: "-10"},
+			{Name: ATTR_r, Value: "n"},
+		},
+	}
+	if !reflect.DeepEqual(o, expected) {
+		t.Errorf("ParseDirectives returned\n%+v\nexpected\n%+v\n", o, expected)
+	}
+
+	o, err = ParseDirectives(strings.NewReader("#!/bin/sh\n#PBS -N ignored\n#$ -N custom\n"), "#$")
+	if err != nil || !reflect.DeepEqual(o.Attribs, []Attrib{{Name: ATTR_N, Value: "custom"}}) {
+		t.Errorf("ParseDirectives with a custom prefix returned %+v, %v\n", o, err)
+	}
+}
+
+func TestParseDirectivesErrors(t *testing.T) {
+	tests := []struct {
+		script string
+		msg    string
+	}{
+		{"#PBS -N a\n#PBS -x\n", "pbs: line 2: -x: unsupported option, interactive jobs can't be submitted"},
+		{"#PBS -Y\n", "pbs: line 1: -Y: unsupported option"},
+		{"#PBS -C '#$'\n", "pbs: line 1: -C: only allowed on the command line"},
+		{"#!/bin/sh\n\n#PBS -l walltime\n", `pbs: line 3: -l: invalid resource "walltime"`},
+		{"#PBS -j x\n", `pbs: line 1: -j: invalid value "x", expected one of oe, eo, n`},
+		{"#PBS -p 2000\n", `pbs: line 1: -p: invalid priority "2000", expected -1024 to 1023`},
+		{"#PBS -m abz\n", `pbs: line 1: -m: invalid mail points "abz"`},
+		{"#PBS -t 5-1\n", `pbs: line 1: -t: invalid array range "5-1"`},
+		{"#PBS -W depend=later:1\n", `pbs: line 1: -W: unknown dependency type "later"`},
+		{"#PBS -W stageout=out.dat\n", `pbs: line 1: -W: missing host in stage file "out.dat"`},
+		{"#PBS -N\n", "pbs: line 1: -N: missing argument"},
+		{"#PBS -N 'unterminated\n", "pbs: line 1: unterminated quote"},
+		{"#PBS -N a extra\n", `pbs: line 1: unexpected argument "extra"`},
+	}
+	for _, test := range tests {
+		_, err := ParseDirectives(strings.NewReader(test.script), "")
+		if err == nil || err.Error() != test.msg {
+			t.Errorf("ParseDirectives(%q) returned %v, expected %s\n", test.script, err, test.msg)
+		}
+	}
+
+	_, err := ParseDirectives(strings.NewReader("#PBS -x\n"), "")
+	var oerr *OptionError
+	if !errors.As(err, &oerr) || oerr.Line != 1 || oerr.Option != "-x" || !errors.Is(err, ErrUnsupportedOption) {
+		t.Errorf("ParseDirectives returned %#v\n", err)
+	}
+}
@@ -0,0 +1,435 @@
+// Package dis encodes and decodes the DIS (Data-Is-Strings) values used by
+// TORQUE's batch protocol.
+//
+// An unsigned integer is written as a sign and its decimal digits, preceded
+// by the number of digits if there is more than one, which is in turn
+// preceded by its own length and so on: 5 is "+5", 123 is "3+123" and
+// 1234567890 is "210+1234567890". Signed integers are the same with a "-"
+// for negative values. A counted string is its length, as an unsigned
+// integer, followed by the raw bytes. A floating point number is its
+// significant digits, as a signed integer, followed by the signed power of
+// ten they are multiplied by.
+//
+// The attribute lists and status lists which libtorque sends and receives
+// for attrl, attropl and batch_status structures are built from these, and
+// are handled by the Encoder's WriteAttrs and WriteStatuses methods and the
+// Decoder's ReadAttrs and ReadStatuses methods.
+package dis
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// DefaultMaxString is the default limit on the size of strings the Decoder
+// will read
+const DefaultMaxString = 64 << 20
+
+// maxIntDigits is the most digits an integer which fits in 64 bits can have
+const maxIntDigits = 20
+
+// maxFloatDigits is the most significant digits accepted for a float
+const maxFloatDigits = 64
+
+var (
+	// ErrMalformed is returned when the data isn't DIS encoded
+	ErrMalformed = errors.New("dis: malformed data")
+
+	// ErrOverflow is returned when a number doesn't fit the type read
+	ErrOverflow = errors.New("dis: value out of range")
+
+	// ErrBadSign is returned when reading a negative unsigned integer
+	ErrBadSign = errors.New("dis: negative unsigned integer")
+
+	// ErrTooLong is returned for strings longer than the Decoder's MaxString
+	ErrTooLong = errors.New("dis: string too long")
+
+	// ErrNotFinite is returned when writing an infinite or NaN float, which
+	// can't be represented
+	ErrNotFinite = errors.New("dis: float is not finite")
+)
+
+// Attr is an entry of an attribute list, the svrattrl, attrl and attropl
+// structures. Op is the batch_op value, SET is 0.
+type Attr struct {
+	Name     string
+	Resource string
+	Value    string
+	Op       uint64
+}
+
+// Status is an entry of the list in a status reply, the batch_status
+// structure
+type Status struct {
+	ObjType uint64
+	Name    string
+	Attrs   []Attr
+}
+
+func appendNumber(dst []byte, sign byte, digits string) []byte {
+	var prefix []byte
+	for n := len(digits); n > 1; {
+		count := strconv.Itoa(n)
+		prefix = append([]byte(count), prefix...)
+		n = len(count)
+	}
+	dst = append(dst, prefix...)
+	dst = append(dst, sign)
+	return append(dst, digits...)
+}
+
+// AppendUint appends the encoding of v to dst
+func AppendUint(dst []byte, v uint64) []byte {
+	return appendNumber(dst, '+', strconv.FormatUint(v, 10))
+}
+
+// AppendInt appends the encoding of v to dst
+func AppendInt(dst []byte, v int64) []byte {
+	if v < 0 {
+		// Negate as unsigned, so that math.MinInt64 works
+		return appendNumber(dst, '-', strconv.FormatUint(-uint64(v), 10))
+	}
+	return appendNumber(dst, '+', strconv.FormatUint(uint64(v), 10))
+}
+
+// AppendString appends the encoding of s, as a counted string, to dst
+func AppendString(dst []byte, s string) []byte {
+	dst = AppendUint(dst, uint64(len(s)))
+	return append(dst, s...)
+}
+
+// AppendFloat appends the encoding of f to dst. It returns ErrNotFinite for
+// infinities and NaN.
+func AppendFloat(dst []byte, f float64) ([]byte, error) {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return dst, ErrNotFinite
+	}
+
+	sign := byte('+')
+	if math.Signbit(f) {
+		sign = '-'
+	}
+
+	// The shortest representation which reads back as f, as d.ddde±x
+	s := strconv.FormatFloat(math.Abs(f), 'e', -1, 64)
+	mantissa, exponent := s, 0
+	for i := 0; i < len(s); i++ {
+		if s[i] == 'e' {
+			mantissa = s[:i]
+			exponent, _ = strconv.Atoi(s[i+1:])
+			break
+		}
+	}
+
+	digits := make([]byte, 0, len(mantissa))
+	for i := 0; i < len(mantissa); i++ {
+		if mantissa[i] != '.' {
+			digits = append(digits, mantissa[i])
+		}
+	}
+	exponent -= len(digits) - 1
+
+	dst = appendNumber(dst, sign, string(digits))
+	return AppendInt(dst, int64(exponent)), nil
+}
+
+// Encoder writes DIS values to a stream. It is buffered, so Flush must be
+// called once a message has been written. The first error is kept, after
+// which nothing more is written, and is returned by Flush and Err.
+type Encoder struct {
+	w   *bufio.Writer
+	buf []byte
+	err error
+}
+
+// NewEncoder returns an Encoder writing to w
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: bufio.NewWriter(w)}
+}
+
+func (e *Encoder) write(b []byte) {
+	if e.err == nil {
+		_, e.err = e.w.Write(b)
+	}
+}
+
+// WriteUint writes an unsigned integer
+func (e *Encoder) WriteUint(v uint64) {
+	e.buf = AppendUint(e.buf[:0], v)
+	e.write(e.buf)
+}
+
+// WriteInt writes a signed integer
+func (e *Encoder) WriteInt(v int64) {
+	e.buf = AppendInt(e.buf[:0], v)
+	e.write(e.buf)
+}
+
+// WriteString writes a counted string
+func (e *Encoder) WriteString(s string) {
+	e.WriteUint(uint64(len(s)))
+	if e.err == nil {
+		_, e.err = e.w.WriteString(s)
+	}
+}
+
+// WriteFloat writes a floating point number
+func (e *Encoder) WriteFloat(f float64) {
+	var err error
+	e.buf, err = AppendFloat(e.buf[:0], f)
+	if err != nil {
+		if e.err == nil {
+			e.err = err
+		}
+		return
+	}
+	e.write(e.buf)
+}
+
+// WriteAttrs writes an attribute list. Each entry is preceded by its size,
+// the lengths of its strings including their terminating NULs in C.
+func (e *Encoder) WriteAttrs(attrs []Attr) {
+	e.WriteUint(uint64(len(attrs)))
+	for _, a := range attrs {
+		size := len(a.Name) + 1 + len(a.Value) + 1
+		if a.Resource != "" {
+			size += len(a.Resource) + 1
+		}
+		e.WriteUint(uint64(size))
+		e.WriteString(a.Name)
+		if a.Resource != "" {
+			e.WriteUint(1)
+			e.WriteString(a.Resource)
+		} else {
+			e.WriteUint(0)
+		}
+		e.WriteString(a.Value)
+		e.WriteUint(a.Op)
+	}
+}
+
+// WriteStatuses writes the list of objects in a status reply
+func (e *Encoder) WriteStatuses(statuses []Status) {
+	e.WriteUint(uint64(len(statuses)))
+	for _, s := range statuses {
+		e.WriteUint(s.ObjType)
+		e.WriteString(s.Name)
+		e.WriteAttrs(s.Attrs)
+	}
+}
+
+// Flush writes any buffered data, returning the first error encountered
+func (e *Encoder) Flush() error {
+	if e.err == nil {
+		e.err = e.w.Flush()
+	}
+	return e.err
+}
+
+// Err returns the first error encountered
+func (e *Encoder) Err() error {
+	return e.err
+}
+
+// Decoder reads DIS values from a stream. The first error is kept, after
+// which the Read methods return zero values, and is returned by Err: a
+// message can be read in full and checked once.
+type Decoder struct {
+	// MaxString limits the length of strings which will be read, so that
+	// corrupt data can't cause a huge allocation. The default is
+	// DefaultMaxString.
+	MaxString uint64
+
+	r   *bufio.Reader
+	err error
+}
+
+// NewDecoder returns a Decoder reading from r
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReader(r), MaxString: DefaultMaxString}
+}
+
+// Err returns the first error encountered, io.EOF if the stream ended
+// cleanly before a value and io.ErrUnexpectedEOF if it ended within one
+func (d *Decoder) Err() error {
+	return d.err
+}
+
+func (d *Decoder) fail(err error) {
+	if d.err == nil {
+		d.err = err
+	}
+}
+
+func (d *Decoder) malformed(c byte) {
+	d.fail(fmt.Errorf("%w: unexpected %q", ErrMalformed, c))
+}
+
+// digits reads up to n digits, the first of which may already have been
+// read
+func (d *Decoder) digits(buf []byte, n uint64) []byte {
+	for uint64(len(buf)) < n {
+		c, err := d.r.ReadByte()
+		if err != nil {
+			d.fail(io.ErrUnexpectedEOF)
+			return nil
+		}
+		if c < '0' || c > '9' {
+			d.malformed(c)
+			return nil
+		}
+		buf = append(buf, c)
+	}
+	return buf
+}
+
+// number reads the sign and digits of a number with at most max digits
+func (d *Decoder) number(max uint64) (bool, string) {
+	if d.err != nil {
+		return false, ""
+	}
+
+	count := uint64(1)
+	for first := true; ; first = false {
+		c, err := d.r.ReadByte()
+		if err != nil {
+			if !first || err != io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			d.fail(err)
+			return false, ""
+		}
+
+		switch {
+		case c == '+' || c == '-':
+			digits := d.digits(nil, count)
+			return c == '-', string(digits)
+		case c >= '1' && c <= '9':
+			// The number of digits in the next part
+			digits := d.digits([]byte{c}, count)
+			if d.err != nil {
+				return false, ""
+			}
+			count, err = strconv.ParseUint(string(digits), 10, 64)
+			if err != nil || count > max {
+				d.fail(ErrOverflow)
+				return false, ""
+			}
+		default:
+			d.malformed(c)
+			return false, ""
+		}
+	}
+}
+
+// ReadUint reads an unsigned integer
+func (d *Decoder) ReadUint() uint64 {
+	neg, digits := d.number(maxIntDigits)
+	if d.err != nil {
+		return 0
+	}
+	v, err := strconv.ParseUint(digits, 10, 64)
+	if err != nil {
+		d.fail(ErrOverflow)
+		return 0
+	}
+	if neg && v != 0 {
+		d.fail(ErrBadSign)
+		return 0
+	}
+	return v
+}
+
+// ReadInt reads a signed integer
+func (d *Decoder) ReadInt() int64 {
+	neg, digits := d.number(maxIntDigits)
+	if d.err != nil {
+		return 0
+	}
+	v, err := strconv.ParseUint(digits, 10, 64)
+	switch {
+	case err != nil, neg && v > 1<<63, !neg && v >= 1<<63:
+		d.fail(ErrOverflow)
+		return 0
+	case neg:
+		return -int64(v)
+	}
+	return int64(v)
+}
+
+// ReadString reads a counted string
+func (d *Decoder) ReadString() string {
+	n := d.ReadUint()
+	if d.err != nil {
+		return ""
+	}
+	if n > d.MaxString {
+		d.fail(fmt.Errorf("%w: %d bytes", ErrTooLong, n))
+		return ""
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(d.r, buf); err != nil {
+		d.fail(io.ErrUnexpectedEOF)
+		return ""
+	}
+	return string(buf)
+}
+
+// ReadFloat reads a floating point number
+func (d *Decoder) ReadFloat() float64 {
+	neg, digits := d.number(maxFloatDigits)
+	exponent := d.ReadInt()
+	if d.err != nil {
+		return 0
+	}
+
+	f, err := strconv.ParseFloat(digits+"e"+strconv.FormatInt(exponent, 10), 64)
+	if err != nil {
+		d.fail(ErrOverflow)
+		return 0
+	}
+	if neg {
+		f = -f
+	}
+	return f
+}
+
+// ReadAttrs reads an attribute list
+func (d *Decoder) ReadAttrs() []Attr {
+	n := d.ReadUint()
+	var attrs []Attr
+	for i := uint64(0); i < n && d.err == nil; i++ {
+		var a Attr
+		d.ReadUint() // the size of the entry, which isn't needed
+		a.Name = d.ReadString()
+		if d.ReadUint() != 0 {
+			a.Resource = d.ReadString()
+		}
+		a.Value = d.ReadString()
+		a.Op = d.ReadUint()
+		if d.err == nil {
+			attrs = append(attrs, a)
+		}
+	}
+	return attrs
+}
+
+// ReadStatuses reads the list of objects in a status reply
+func (d *Decoder) ReadStatuses() []Status {
+	n := d.ReadUint()
+	var statuses []Status
+	for i := uint64(0); i < n && d.err == nil; i++ {
+		var s Status
+		s.ObjType = d.ReadUint()
+		s.Name = d.ReadString()
+		s.Attrs = d.ReadAttrs()
+		if d.err == nil {
+			statuses = append(statuses, s)
+		}
+	}
+	return statuses
+}
@@ -0,0 +1,263 @@
+package dis
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIntEncoding(t *testing.T) {
+	tests := []struct {
+		value   int64
+		encoded string
+	}{
+		{0, "+0"},
+		{5, "+5"},
+		{-7, "-7"},
+		{123, "3+123"},
+		{1234567890, "210+1234567890"},
+		{-15001, "5-15001"},
+		{math.MaxInt64, "219+9223372036854775807"},
+		{math.MinInt64, "219-9223372036854775808"},
+	}
+
+	for _, test := range tests {
+		if encoded := string(AppendInt(nil, test.value)); encoded != test.encoded {
+			t.Errorf("%d encoded as %q, expected %q\n", test.value, encoded, test.encoded)
+		}
+
+		d := NewDecoder(strings.NewReader(test.encoded))
+		if v := d.ReadInt(); v != test.value || d.Err() != nil {
+			t.Errorf("%q decoded as %d (%v), expected %d\n", test.encoded, v, d.Err(), test.value)
+		}
+	}
+}
+
+func TestFloatEncoding(t *testing.T) {
+	tests := []struct {
+		value   float64
+		encoded string
+	}{
+		{0, "+0+0"},
+		{1, "+1+0"},
+		{-2.5, "2-25-1"},
+		{1234500, "5+12345+2"},
+		{0.001, "+1-3"},
+	}
+
+	for _, test := range tests {
+		encoded, err := AppendFloat(nil, test.value)
+		if err != nil || string(encoded) != test.encoded {
+			t.Errorf("%g encoded as %q (%v), expected %q\n", test.value, encoded, err, test.encoded)
+		}
+
+		d := NewDecoder(strings.NewReader(test.encoded))
+		if v := d.ReadFloat(); v != test.value || d.Err() != nil {
+			t.Errorf("%q decoded as %g (%v), expected %g\n", test.encoded, v, d.Err(), test.value)
+		}
+	}
+
+	for _, f := range []float64{math.Inf(1), math.Inf(-1), math.NaN()} {
+		if _, err := AppendFloat(nil, f); !errors.Is(err, ErrNotFinite) {
+			t.Errorf("Encoding %g returned %v\n", f, err)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	attrs := []Attr{
+		{Name: "Job_Name", Value: "job"},
+		{Name: "Resource_List", Resource: "walltime", Value: "01:00:00", Op: 4},
+		{Name: "empty"},
+	}
+	statuses := []Status{
+		{ObjType: 2, Name: "1.server", Attrs: attrs},
+		{ObjType: 2, Name: "2.server"},
+	}
+
+	var buf bytes.Buffer
+	e := NewEncoder(&buf)
+	e.WriteUint(math.MaxUint64)
+	e.WriteString("")
+	e.WriteString("hello, world")
+	e.WriteFloat(math.SmallestNonzeroFloat64)
+	e.WriteFloat(-math.MaxFloat64)
+	e.WriteAttrs(attrs)
+	e.WriteAttrs(nil)
+	e.WriteStatuses(statuses)
+	if err := e.Flush(); err != nil {
+		t.Fatalf("Encoding failed: %s\n", err)
+	}
+
+	d := NewDecoder(&buf)
+	if v := d.ReadUint(); v != math.MaxUint64 {
+		t.Errorf("MaxUint64 decoded as %d\n", v)
+	}
+	if s := d.ReadString(); s != "" {
+		t.Errorf("Empty string decoded as %q\n", s)
+	}
+	if s := d.ReadString(); s != "hello, world" {
+		t.Errorf("String decoded as %q\n", s)
+	}
+	if f := d.ReadFloat(); f != math.SmallestNonzeroFloat64 {
+		t.Errorf("SmallestNonzeroFloat64 decoded as %g\n", f)
+	}
+	if f := d.ReadFloat(); f != -math.MaxFloat64 {
+		t.Errorf("-MaxFloat64 decoded as %g\n", f)
+	}
+	if a := d.ReadAttrs(); !reflect.DeepEqual(a, attrs) {
+		t.Errorf("Attributes decoded as %+v\n", a)
+	}
+	if a := d.ReadAttrs(); len(a) != 0 {
+		t.Errorf("Empty attribute list decoded as %+v\n", a)
+	}
+	if s := d.ReadStatuses(); !reflect.DeepEqual(s, statuses) {
+		t.Errorf("Statuses decoded as %+v\n", s)
+	}
+	if d.Err() != nil {
+		t.Errorf("Decoding failed: %s\n", d.Err())
+	}
+	if d.ReadUint(); d.Err() != io.EOF {
+		t.Errorf("Reading past the end returned %v\n", d.Err())
+	}
+}
+
+func TestAttrSize(t *testing.T) {
+	// The size includes the NUL terminators: "a\0", "b\0" and "c\0"
+	var buf bytes.Buffer
+	e := NewEncoder(&buf)
+	e.WriteAttrs([]Attr{{Name: "a", Resource: "b", Value: "c"}})
+	e.Flush()
+	if buf.String() != "+1+6+1a+1+1b+1c+0" {
+		t.Errorf("Attribute encoded as %q\n", buf.String())
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	readUint := func(d *Decoder) { d.ReadUint() }
+	readInt := func(d *Decoder) { d.ReadInt() }
+	readString := func(d *Decoder) { d.ReadString() }
+	readFloat := func(d *Decoder) { d.ReadFloat() }
+
+	tests := []struct {
+		encoded string
+		read    func(*Decoder)
+		err     error
+	}{
+		{"3+12", readUint, io.ErrUnexpectedEOF},
+		{"x", readUint, ErrMalformed},
+		{"+a", readUint, ErrMalformed},
+		{"+", readUint, io.ErrUnexpectedEOF},
+		{"-1", readUint, ErrBadSign},
+		{"9999999999999", readUint, ErrOverflow},
+		{"221+123456789012345678901", readUint, ErrOverflow},
+		{"220+18446744073709551616", readUint, ErrOverflow},
+		{"219+9223372036854775808", readInt, ErrOverflow},
+		{"+5ab", readString, io.ErrUnexpectedEOF},
+		{"+14+9999", readFloat, ErrOverflow},
+		{"+1+", readFloat, io.ErrUnexpectedEOF},
+	}
+
+	for _, test := range tests {
+		d := NewDecoder(strings.NewReader(test.encoded))
+		test.read(d)
+		if !errors.Is(d.Err(), test.err) {
+			t.Errorf("Decoding %q returned %v, expected %v\n", test.encoded, d.Err(), test.err)
+		}
+	}
+
+	d := NewDecoder(strings.NewReader("3+100" + strings.Repeat("x", 100)))
+	d.MaxString = 10
+	if d.ReadString(); !errors.Is(d.Err(), ErrTooLong) {
+		t.Errorf("Reading long string returned %v\n", d.Err())
+	}
+}
+
+func FuzzInt(f *testing.F) {
+	for _, v := range []int64{0, 1, -1, 123, math.MaxInt64, math.MinInt64} {
+		f.Add(v)
+	}
+	f.Fuzz(func(t *testing.T, v int64) {
+		d := NewDecoder(bytes.NewReader(AppendInt(nil, v)))
+		if got := d.ReadInt(); got != v || d.Err() != nil {
+			t.Errorf("%d decoded as %d (%v)\n", v, got, d.Err())
+		}
+	})
+}
+
+func FuzzUint(f *testing.F) {
+	for _, v := range []uint64{0, 1, 9, 10, math.MaxUint64} {
+		f.Add(v)
+	}
+	f.Fuzz(func(t *testing.T, v uint64) {
+		d := NewDecoder(bytes.NewReader(AppendUint(nil, v)))
+		if got := d.ReadUint(); got != v || d.Err() != nil {
+			t.Errorf("%d decoded as %d (%v)\n", v, got, d.Err())
+		}
+	})
+}
+
+func FuzzString(f *testing.F) {
+	for _, s := range []string{"", "a", "+5", strings.Repeat("x", 1000)} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		d := NewDecoder(bytes.NewReader(AppendString(nil, s)))
+		if got := d.ReadString(); got != s || d.Err() != nil {
+			t.Errorf("%q decoded as %q (%v)\n", s, got, d.Err())
+		}
+	})
+}
+
+func FuzzFloat(f *testing.F) {
+	for _, v := range []float64{0, math.Copysign(0, -1), 1.5, -1e300, math.SmallestNonzeroFloat64} {
+		f.Add(v)
+	}
+	f.Fuzz(func(t *testing.T, v float64) {
+		encoded, err := AppendFloat(nil, v)
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			if err == nil {
+				t.Errorf("Encoding %g didn't fail\n", v)
+			}
+			return
+		}
+
+		d := NewDecoder(bytes.NewReader(encoded))
+		got := d.ReadFloat()
+		if math.Float64bits(got) != math.Float64bits(v) || d.Err() != nil {
+			t.Errorf("%g encoded as %q decoded as %g (%v)\n", v, encoded, got, d.Err())
+		}
+	})
+}
+
+// FuzzDecode checks that arbitrary input doesn't cause a panic, and that
+// any attribute list which is decoded encodes to something that decodes
+// to the same list
+func FuzzDecode(f *testing.F) {
+	f.Add([]byte("+1+6+1a+1+1b+1c+0"))
+	f.Add([]byte("+2+8+4name+0+3val+0+3+1x+0+0+1"))
+	f.Add([]byte("3+12"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		d := NewDecoder(bytes.NewReader(data))
+		d.MaxString = 1 << 16
+		attrs := d.ReadAttrs()
+		if d.Err() != nil {
+			return
+		}
+
+		var buf bytes.Buffer
+		e := NewEncoder(&buf)
+		e.WriteAttrs(attrs)
+		if err := e.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		again := NewDecoder(&buf).ReadAttrs()
+		if len(attrs) != 0 && !reflect.DeepEqual(again, attrs) {
+			t.Errorf("%+v decoded again as %+v\n", attrs, again)
+		}
+	})
+}
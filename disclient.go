@@ -0,0 +1,530 @@
+package pbs
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jbarber/pbs/dis"
+)
+
+// Batch protocol identifiers, from TORQUE's libpbs.h and batch_request.h
+const (
+	batchProtType = 2
+	batchProtVer  = 1
+
+	batchQueueJob    = 1
+	batchJobScript   = 3
+	batchRdytoCommit = 4
+	batchCommit      = 5
+	batchDeleteJob   = 6
+	batchHoldJob     = 7
+	batchManager     = 9
+	batchModifyJob   = 11
+	batchMoveJob     = 12
+	batchReleaseJob  = 13
+	batchSelectJobs  = 16
+	batchSignalJob   = 18
+	batchStatusJob   = 19
+	batchStatusQue   = 20
+	batchStatusSvr   = 21
+	batchSelStat     = 51
+	batchStatusNode  = 58
+	batchDisconnect  = 59
+
+	replyNull     = 1
+	replyQueue    = 2
+	replyRdytoCom = 3
+	replyCommit   = 4
+	replySelect   = 5
+	replyStatus   = 6
+	replyText     = 7
+	replyLocate   = 8
+
+	// jobScriptChunk is how much of the job script is sent in each
+	// JobScript request
+	jobScriptChunk = 4096
+)
+
+// DefaultPort is the port pbs_server listens on
+const DefaultPort = 15001
+
+// DIS is a Backend which speaks TORQUE's batch protocol to the server
+// directly, so needs neither libtorque nor cgo.
+//
+// The server needs to know which user is making requests. As libtorque
+// does, the default is to run pbs_iff to vouch for the connection, which
+// only works with servers using pbs_iff (TORQUE before 4.0) rather than
+// trqauthd. Authenticate can be set for other arrangements.
+type DIS struct {
+	// Port is used for servers given without a port, the default is
+	// DefaultPort
+	Port int
+
+	// User is sent with each request, the default is the current user
+	User string
+
+	// Timeout limits the time taken to connect, the default is no limit
+	Timeout time.Duration
+
+	// Authenticate is called with each new connection to prove the
+	// identity of User to the server at host:port, the default runs
+	// pbs_iff
+	Authenticate func(conn net.Conn, host string, port int) error
+}
+
+// DefaultServer returns the server used when Connect is given an empty
+// server name, from $PBS_DEFAULT, $PBS_SERVER or the server_name file
+func (DIS) DefaultServer() string {
+	return disDefaultServer()
+}
+
+// Connect opens a connection to server, which can include a port, e.g.
+// "torque.example.com:15001". An empty server selects the default server
+// as Pbs_default does.
+func (b DIS) Connect(server string) (Client, error) {
+	if server == "" {
+		server = disDefaultServer()
+	}
+	if server == "" {
+		return nil, &PBSError{Op: "pbs_connect", Errno: PBSE_NOSERVER, Msg: errorText[PBSE_NOSERVER]}
+	}
+
+	host, port := splitServer(server, b.Port)
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, strconv.Itoa(port)), b.Timeout)
+	if err != nil {
+		return nil, &PBSError{Op: "pbs_connect", ID: server, Errno: PBSE_NOSERVER, Msg: errorText[PBSE_NOSERVER], Err: err}
+	}
+
+	auth := b.Authenticate
+	if auth == nil {
+		auth = pbsIff
+	}
+	if err := auth(conn, host, port); err != nil {
+		conn.Close()
+		return nil, &PBSError{Op: "pbs_connect", ID: server, Errno: PBSE_PERM, Msg: errorText[PBSE_PERM], Err: err}
+	}
+
+	return newDISClient(conn, b.User), nil
+}
+
+func newDISClient(conn net.Conn, username string) *disClient {
+	if username == "" {
+		username = os.Getenv("USER")
+		if u, err := user.Current(); err == nil {
+			username = u.Username
+		}
+	}
+	return &disClient{
+		conn: conn,
+		sock: conn,
+		user: username,
+		r:    dis.NewDecoder(conn),
+		w:    dis.NewEncoder(conn),
+	}
+}
+
+// splitServer separates the port from server, using port, or DefaultPort,
+// if there isn't one
+func splitServer(server string, port int) (string, int) {
+	if port == 0 {
+		port = DefaultPort
+	}
+	if host, p, err := net.SplitHostPort(server); err == nil {
+		if n, err := strconv.Atoi(p); err == nil {
+			return host, n
+		}
+	}
+	return server, port
+}
+
+// disDefaultServer finds the default server in the same places as
+// pbs_default: the environment, then the server_name file
+func disDefaultServer() string {
+	if servers := ParseServerList(disServerList()); len(servers) > 0 {
+		return servers[0]
+	}
+	return ""
+}
+
+// disServerList returns the list of servers, as pbs_get_server_list does
+func disServerList() string {
+	for _, env := range []string{"PBS_DEFAULT", "PBS_SERVER"} {
+		if s := os.Getenv(env); s != "" {
+			return s
+		}
+	}
+
+	home := os.Getenv("PBS_SERVER_HOME")
+	if home == "" {
+		home = "/var/spool/torque"
+	}
+	data, err := os.ReadFile(filepath.Join(home, "server_name"))
+	if err != nil {
+		return ""
+	}
+	return strings.SplitN(string(data), "\n", 2)[0]
+}
+
+// pbsIff authenticates conn the way libtorque does, by running pbs_iff.
+// pbs_iff is given the connection as file descriptor 3 to find its local
+// port, then tells the server from a privileged port which user owns it.
+func pbsIff(conn net.Conn, host string, port int) error {
+	tcp, ok := conn.(*net.TCPConn)
+	if !ok {
+		return errors.New("pbs_iff needs a TCP connection")
+	}
+	f, err := tcp.File()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	cmd := exec.Command("pbs_iff", host, strconv.Itoa(port), "3")
+	cmd.ExtraFiles = []*os.File{f}
+	out, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("pbs_iff: %w", err)
+	}
+	// pbs_iff writes its integer status to stdout
+	for _, b := range out {
+		if b != 0 {
+			return fmt.Errorf("pbs_iff failed with status %v", out)
+		}
+	}
+	return nil
+}
+
+// disClient is a Client using a DIS connection
+type disClient struct {
+	mu   sync.Mutex
+	conn net.Conn
+	user string
+	r    *dis.Decoder
+	w    *dis.Encoder
+
+	// sock is conn, kept for abort as conn is guarded by mu
+	sock net.Conn
+}
+
+var _ Client = (*disClient)(nil)
+
+// abort closes the connection without waiting for a request in progress,
+// which then fails
+func (c *disClient) abort() {
+	c.sock.Close()
+}
+
+// disReply is the decoded batch reply, only the fields for its choice are
+// set
+type disReply struct {
+	code   int
+	aux    int
+	choice uint64
+	jobid  string
+	ids    []string
+	status []BatchStatus
+	text   string
+}
+
+func protocolError(op string, id string, err error) error {
+	return &PBSError{Op: op, ID: id, Errno: PBSE_PROTOCOL, Msg: errorText[PBSE_PROTOCOL], Err: err}
+}
+
+// request sends a batch request, with body encoding the request specific
+// part, and reads the reply. The server's errors are returned as a
+// *PBSError with the server's errno and message.
+func (c *disClient) request(op string, id string, reqType uint64, extend string, body func(w *dis.Encoder)) (*disReply, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		return nil, protocolError(op, id, net.ErrClosed)
+	}
+
+	w := c.w
+	w.WriteUint(batchProtType)
+	w.WriteUint(batchProtVer)
+	w.WriteUint(reqType)
+	w.WriteString(c.user)
+	if body != nil {
+		body(w)
+	}
+	if extend == "" {
+		w.WriteUint(0)
+	} else {
+		w.WriteUint(1)
+		w.WriteString(extend)
+	}
+	if err := w.Flush(); err != nil {
+		return nil, protocolError(op, id, err)
+	}
+
+	reply, err := c.reply()
+	if err != nil {
+		return nil, protocolError(op, id, err)
+	}
+	if reply.code != 0 {
+		return nil, &PBSError{
+			Op:        op,
+			ID:        id,
+			Errno:     reply.code,
+			Msg:       errorText[reply.code],
+			ServerMsg: reply.text,
+		}
+	}
+	return reply, nil
+}
+
+func (c *disClient) reply() (*disReply, error) {
+	r := c.r
+	reply := &disReply{}
+
+	if t := r.ReadUint(); r.Err() == nil && t != batchProtType {
+		return nil, fmt.Errorf("%w: reply has protocol type %d", dis.ErrMalformed, t)
+	}
+	r.ReadUint() // protocol version
+	reply.code = int(r.ReadInt())
+	reply.aux = int(r.ReadInt())
+	reply.choice = r.ReadUint()
+	if r.Err() != nil {
+		return nil, r.Err()
+	}
+
+	switch reply.choice {
+	case replyNull:
+	case replyQueue, replyRdytoCom, replyCommit, replyLocate:
+		reply.jobid = r.ReadString()
+	case replySelect:
+		n := r.ReadUint()
+		for i := uint64(0); i < n && r.Err() == nil; i++ {
+			reply.ids = append(reply.ids, r.ReadString())
+		}
+	case replyStatus:
+		for _, s := range r.ReadStatuses() {
+			reply.status = append(reply.status, BatchStatus{
+				Name:       s.Name,
+				Attributes: fromDISAttrs(s.Attrs),
+			})
+		}
+	case replyText:
+		reply.text = r.ReadString()
+	default:
+		return nil, fmt.Errorf("%w: unknown reply choice %d", dis.ErrMalformed, reply.choice)
+	}
+	return reply, r.Err()
+}
+
+// disAttrs converts attribs for encoding
+func disAttrs(attribs []Attrib) []dis.Attr {
+	attrs := make([]dis.Attr, len(attribs))
+	for i, a := range attribs {
+		attrs[i] = dis.Attr{Name: a.Name, Resource: a.Resource, Value: a.Value, Op: uint64(a.Op)}
+	}
+	return attrs
+}
+
+// fromDISAttrs converts decoded attributes
+func fromDISAttrs(attrs []dis.Attr) []Attrib {
+	var attribs []Attrib
+	for _, a := range attrs {
+		attribs = append(attribs, Attrib{Name: a.Name, Resource: a.Resource, Value: a.Value, Op: Operator(a.Op)})
+	}
+	return attribs
+}
+
+func (c *disClient) Submit(attribs []Attrib, script string, destination string, extend string) (string, error) {
+	data, err := os.ReadFile(script)
+	if err != nil {
+		return "", &PBSError{Op: "pbs_submit", ID: script, Errno: PBSE_BADSCRIPT, Msg: errorText[PBSE_BADSCRIPT], Err: err}
+	}
+	return c.submit(attribs, data, destination, extend)
+}
+
+// SubmitScript submits a job with the script's contents, which are sent to
+// the server without being written to a file
+func (c *disClient) SubmitScript(attribs []Attrib, script []byte, destination string, extend string) (string, error) {
+	return c.submit(attribs, script, destination, extend)
+}
+
+// submit queues a job with the given script, going through the same
+// QueueJob, JobScript, RdytoCommit and Commit steps as pbs_submit
+func (c *disClient) submit(attribs []Attrib, script []byte, destination string, extend string) (string, error) {
+	reply, err := c.request("pbs_submit", "", batchQueueJob, extend, func(w *dis.Encoder) {
+		w.WriteString("")
+		w.WriteString(destination)
+		w.WriteAttrs(disAttrs(attribs))
+	})
+	if err != nil {
+		return "", err
+	}
+	jobid := reply.jobid
+
+	for seq := 0; len(script) > 0; seq++ {
+		chunk := script
+		if len(chunk) > jobScriptChunk {
+			chunk = chunk[:jobScriptChunk]
+		}
+		script = script[len(chunk):]
+
+		_, err := c.request("pbs_submit", jobid, batchJobScript, "", func(w *dis.Encoder) {
+			w.WriteUint(uint64(seq))
+			w.WriteUint(0) // JScript, the job script rather than stdin/stdout/stderr
+			w.WriteUint(uint64(len(chunk)))
+			w.WriteString(jobid)
+			w.WriteString(string(chunk))
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+
+	for _, step := range []uint64{batchRdytoCommit, batchCommit} {
+		_, err := c.request("pbs_submit", jobid, step, "", func(w *dis.Encoder) {
+			w.WriteString(jobid)
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return jobid, nil
+}
+
+func (c *disClient) status(op string, reqType uint64, id string, attribs []Attrib, extend string) ([]BatchStatus, error) {
+	reply, err := c.request(op, id, reqType, extend, func(w *dis.Encoder) {
+		w.WriteString(id)
+		w.WriteAttrs(disAttrs(attribs))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reply.status, nil
+}
+
+func (c *disClient) StatJob(id string, attribs []Attrib, extend string) ([]BatchStatus, error) {
+	return c.status("pbs_statjob", batchStatusJob, id, attribs, extend)
+}
+
+func (c *disClient) StatNode(id string, attribs []Attrib, extend string) ([]BatchStatus, error) {
+	return c.status("pbs_statnode", batchStatusNode, id, attribs, extend)
+}
+
+func (c *disClient) StatQue(id string, attribs []Attrib, extend string) ([]BatchStatus, error) {
+	return c.status("pbs_statque", batchStatusQue, id, attribs, extend)
+}
+
+func (c *disClient) StatServer(attribs []Attrib, extend string) ([]BatchStatus, error) {
+	return c.status("pbs_statserver", batchStatusSvr, "", attribs, extend)
+}
+
+func (c *disClient) SelectJob(attribs []Attrib, extend string) ([]string, error) {
+	reply, err := c.request("pbs_selectjob", "", batchSelectJobs, extend, func(w *dis.Encoder) {
+		w.WriteAttrs(disAttrs(attribs))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reply.ids, nil
+}
+
+func (c *disClient) SelStat(attribs []Attrib, extend string) ([]BatchStatus, error) {
+	reply, err := c.request("pbs_selstat", "", batchSelStat, extend, func(w *dis.Encoder) {
+		w.WriteAttrs(disAttrs(attribs))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reply.status, nil
+}
+
+// manage sends one of the requests which share the Manager request's
+// encoding
+func (c *disClient) manage(op string, reqType uint64, command Command, objType ObjectType, name string, attribs []Attrib, extend string) error {
+	_, err := c.request(op, name, reqType, extend, func(w *dis.Encoder) {
+		w.WriteUint(uint64(command))
+		w.WriteUint(uint64(uint32(objType)))
+		w.WriteString(name)
+		w.WriteAttrs(disAttrs(attribs))
+	})
+	return err
+}
+
+func holdAttribs(holdType Hold) []Attrib {
+	// As with libtorque, no hold type means a user hold
+	if holdType == "" {
+		holdType = USER_HOLD
+	}
+	return []Attrib{{Name: ATTR_h, Value: string(holdType), Op: SET}}
+}
+
+func (c *disClient) HoldJob(id string, holdType Hold, extend string) error {
+	return c.manage("pbs_holdjob", batchHoldJob, MGR_CMD_SET, MGR_OBJ_JOB, id, holdAttribs(holdType), extend)
+}
+
+func (c *disClient) RlsJob(id string, holdType Hold, extend string) error {
+	return c.manage("pbs_rlsjob", batchReleaseJob, MGR_CMD_SET, MGR_OBJ_JOB, id, holdAttribs(holdType), extend)
+}
+
+func (c *disClient) DelJob(id string, extend string) error {
+	return c.manage("pbs_deljob", batchDeleteJob, MGR_CMD_DELETE, MGR_OBJ_JOB, id, nil, extend)
+}
+
+func (c *disClient) AlterJob(id string, attribs []Attrib, extend string) error {
+	return c.manage("pbs_alterjob", batchModifyJob, MGR_CMD_SET, MGR_OBJ_JOB, id, attribs, extend)
+}
+
+func (c *disClient) Manager(command Command, objType ObjectType, name string, attribs []Attrib, extend string) error {
+	return c.manage("pbs_manager", batchManager, command, objType, name, attribs, extend)
+}
+
+func (c *disClient) MoveJob(id string, destination string, extend string) error {
+	_, err := c.request("pbs_movejob", id, batchMoveJob, extend, func(w *dis.Encoder) {
+		w.WriteString(id)
+		w.WriteString(destination)
+	})
+	return err
+}
+
+func (c *disClient) SigJob(id string, signal string, extend string) error {
+	_, err := c.request("pbs_sigjob", id, batchSignalJob, extend, func(w *dis.Encoder) {
+		w.WriteString(id)
+		w.WriteString(signal)
+	})
+	return err
+}
+
+// Disconnect tells the server the connection is finished and closes it
+func (c *disClient) Disconnect() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		return protocolError("pbs_disconnect", "", net.ErrClosed)
+	}
+
+	w := c.w
+	w.WriteUint(batchProtType)
+	w.WriteUint(batchProtVer)
+	w.WriteUint(batchDisconnect)
+	w.WriteString(c.user)
+	werr := w.Flush()
+
+	err := c.conn.Close()
+	c.conn = nil
+	if werr != nil {
+		err = werr
+	}
+	if err != nil {
+		return protocolError("pbs_disconnect", "", err)
+	}
+	return nil
+}
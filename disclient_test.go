@@ -0,0 +1,337 @@
+package pbs
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jbarber/pbs/dis"
+)
+
+// disStandIn is a minimal pbs_server for testing the DIS client. It decodes
+// batch requests and answers them from a FakeServer.
+type disStandIn struct {
+	fake     *FakeServer
+	listener net.Listener
+
+	mu      sync.Mutex
+	users   []string
+	scripts map[string]string
+	// disconnects receives whether each disconnect request had more than
+	// a header
+	disconnects chan bool
+}
+
+func newDISStandIn(t *testing.T) *disStandIn {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Couldn't listen: %s\n", err)
+	}
+	s := &disStandIn{
+		fake:        NewFakeServer("fake"),
+		listener:    listener,
+		scripts:     map[string]string{},
+		disconnects: make(chan bool, 1),
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *disStandIn) connect(t *testing.T) Client {
+	backend := DIS{
+		User:         "tester",
+		Authenticate: func(net.Conn, string, int) error { return nil },
+	}
+	client, err := backend.Connect(s.listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Connect to stand-in server failed: %s\n", err)
+	}
+	return client
+}
+
+func (s *disStandIn) reply(w *dis.Encoder, err error, choice uint64, body func()) {
+	w.WriteUint(batchProtType)
+	w.WriteUint(batchProtVer)
+
+	var pe *PBSError
+	if errors.As(err, &pe) {
+		w.WriteInt(int64(pe.Errno))
+		w.WriteInt(0)
+		w.WriteUint(replyText)
+		w.WriteString(pe.ServerMsg)
+	} else {
+		w.WriteInt(0)
+		w.WriteInt(0)
+		w.WriteUint(choice)
+		if body != nil {
+			body()
+		}
+	}
+	w.Flush()
+}
+
+func (s *disStandIn) replyStatus(w *dis.Encoder, batch []BatchStatus, err error) {
+	s.reply(w, err, replyStatus, func() {
+		var statuses []dis.Status
+		for _, b := range batch {
+			statuses = append(statuses, dis.Status{
+				ObjType: uint64(MGR_OBJ_JOB),
+				Name:    b.Name,
+				Attrs:   disAttrs(b.Attributes),
+			})
+		}
+		w.WriteStatuses(statuses)
+	})
+}
+
+func (s *disStandIn) serve(conn net.Conn) {
+	defer conn.Close()
+
+	client, _ := s.fake.Connect("")
+	r := dis.NewDecoder(conn)
+	w := dis.NewEncoder(conn)
+	extend := func() string {
+		if r.ReadUint() != 0 {
+			return r.ReadString()
+		}
+		return ""
+	}
+
+	for r.Err() == nil {
+		r.ReadUint() // protocol type
+		r.ReadUint() // protocol version
+		reqType := r.ReadUint()
+		user := r.ReadString()
+		if r.Err() != nil {
+			return
+		}
+		s.mu.Lock()
+		s.users = append(s.users, user)
+		s.mu.Unlock()
+
+		switch reqType {
+		case batchQueueJob:
+			r.ReadString()
+			destination := r.ReadString()
+			attribs := fromDISAttrs(r.ReadAttrs())
+			id, err := client.Submit(attribs, "STDIN", destination, extend())
+			s.reply(w, err, replyQueue, func() { w.WriteString(id) })
+		case batchJobScript:
+			r.ReadUint()
+			r.ReadUint()
+			r.ReadUint()
+			id := r.ReadString()
+			data := r.ReadString()
+			extend()
+			s.mu.Lock()
+			s.scripts[id] += data
+			s.mu.Unlock()
+			s.reply(w, nil, replyNull, nil)
+		case batchRdytoCommit, batchCommit:
+			id := r.ReadString()
+			extend()
+			choice := uint64(replyRdytoCom)
+			if reqType == batchCommit {
+				choice = replyCommit
+			}
+			s.reply(w, nil, choice, func() { w.WriteString(id) })
+		case batchStatusJob, batchStatusQue, batchStatusNode, batchStatusSvr:
+			id := r.ReadString()
+			attribs := fromDISAttrs(r.ReadAttrs())
+			ext := extend()
+			var batch []BatchStatus
+			var err error
+			switch reqType {
+			case batchStatusJob:
+				batch, err = client.StatJob(id, attribs, ext)
+			case batchStatusQue:
+				batch, err = client.StatQue(id, attribs, ext)
+			case batchStatusNode:
+				batch, err = client.StatNode(id, attribs, ext)
+			default:
+				batch, err = client.StatServer(attribs, ext)
+			}
+			s.replyStatus(w, batch, err)
+		case batchSelectJobs:
+			ids, err := client.SelectJob(fromDISAttrs(r.ReadAttrs()), extend())
+			s.reply(w, err, replySelect, func() {
+				w.WriteUint(uint64(len(ids)))
+				for _, id := range ids {
+					w.WriteString(id)
+				}
+			})
+		case batchSelStat:
+			batch, err := client.SelStat(fromDISAttrs(r.ReadAttrs()), extend())
+			s.replyStatus(w, batch, err)
+		case batchDeleteJob, batchHoldJob, batchReleaseJob, batchModifyJob, batchManager:
+			command := Command(r.ReadUint())
+			objType := ObjectType(int32(r.ReadUint()))
+			name := r.ReadString()
+			attribs := fromDISAttrs(r.ReadAttrs())
+			ext := extend()
+			var err error
+			switch reqType {
+			case batchDeleteJob:
+				err = client.DelJob(name, ext)
+			case batchHoldJob:
+				err = client.HoldJob(name, Hold(attribs[0].Value), ext)
+			case batchReleaseJob:
+				err = client.RlsJob(name, Hold(attribs[0].Value), ext)
+			case batchModifyJob:
+				err = client.AlterJob(name, attribs, ext)
+			default:
+				err = client.Manager(command, objType, name, attribs, ext)
+			}
+			s.reply(w, err, replyNull, nil)
+		case batchMoveJob, batchSignalJob:
+			id := r.ReadString()
+			arg := r.ReadString()
+			ext := extend()
+			var err error
+			if reqType == batchMoveJob {
+				err = client.MoveJob(id, arg, ext)
+			} else {
+				err = client.SigJob(id, arg, ext)
+			}
+			s.reply(w, err, replyNull, nil)
+		case batchDisconnect:
+			// pbs_disconnect sends only the header, so the connection
+			// is closed after it
+			r.ReadUint()
+			select {
+			case s.disconnects <- r.Err() == nil:
+			default:
+			}
+			return
+		default:
+			s.reply(w, errnoError(PBSE_UNKREQ), replyNull, nil)
+		}
+	}
+}
+
+func TestDISClient(t *testing.T) {
+	s := newDISStandIn(t)
+	client := s.connect(t)
+
+	script := filepath.Join(t.TempDir(), "test.sh")
+	body := "#!/bin/bash\n" + strings.Repeat("echo hello\n", 1000)
+	if err := os.WriteFile(script, []byte(body), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	jobid, err := client.Submit([]Attrib{
+		{Name: ATTR_N, Value: "dis"},
+		{Name: ATTR_l, Resource: "walltime", Value: "00:10:00"},
+	}, script, "", "")
+	if err != nil {
+		t.Fatalf("Job submission failed: %s\n", err)
+	}
+	s.mu.Lock()
+	received := s.scripts[jobid]
+	s.mu.Unlock()
+	if received != body {
+		t.Errorf("Server received a script of %d bytes, expected %d\n", len(received), len(body))
+	}
+
+	streamed, err := SubmitBytes(client, nil, []byte(body), "", "")
+	if err != nil {
+		t.Fatalf("SubmitBytes failed: %s\n", err)
+	}
+	s.mu.Lock()
+	received = s.scripts[streamed]
+	s.mu.Unlock()
+	if received != body {
+		t.Errorf("Server received a streamed script of %d bytes, expected %d\n", len(received), len(body))
+	}
+
+	if err := client.HoldJob(jobid, "", ""); err != nil {
+		t.Errorf("Hold failed: %s\n", err)
+	}
+	if state := jobAttribute(t, client, jobid, ATTR_state); state != "H" {
+		t.Errorf("Held job is in state %s\n", state)
+	}
+	if err := client.RlsJob(jobid, USER_HOLD, ""); err != nil {
+		t.Errorf("Release failed: %s\n", err)
+	}
+	if err := client.AlterJob(jobid, []Attrib{{Name: ATTR_N, Value: "renamed"}}, ""); err != nil {
+		t.Errorf("Alter failed: %s\n", err)
+	}
+	if name := jobAttribute(t, client, jobid, ATTR_N); name != "renamed" {
+		t.Errorf("Job_Name is %s\n", name)
+	}
+
+	jobs, err := client.SelectJob([]Attrib{{Name: ATTR_N, Value: "renamed", Op: EQ}}, "")
+	if err != nil || len(jobs) != 1 || jobs[0] != jobid {
+		t.Errorf("SelectJob returned %v, %v\n", jobs, err)
+	}
+
+	if err := client.Manager(MGR_CMD_CREATE, MGR_OBJ_QUEUE, "long", []Attrib{{Name: "enabled", Value: "True"}}, ""); err != nil {
+		t.Errorf("Creating queue failed: %s\n", err)
+	}
+	if err := client.MoveJob(jobid, "long", ""); err != nil {
+		t.Errorf("Move failed: %s\n", err)
+	}
+	queues, err := client.StatQue("", nil, "")
+	if err != nil || len(queues) != 2 {
+		t.Errorf("StatQue returned %v, %v\n", queues, err)
+	}
+
+	err = client.DelJob("99.fake", "")
+	if !errors.Is(err, ErrUnknownJob) {
+		t.Errorf("Deleting unknown job returned %v\n", err)
+	}
+	var pe *PBSError
+	if errors.As(err, &pe) && pe.ServerMsg != "Unknown Job Id 99.fake" {
+		t.Errorf("Unexpected server message %q\n", pe.ServerMsg)
+	}
+
+	if err := client.DelJob(jobid, ""); err != nil {
+		t.Errorf("Delete failed: %s\n", err)
+	}
+	if err := client.Disconnect(); err != nil {
+		t.Errorf("Disconnect failed: %s\n", err)
+	}
+	if _, err := client.StatServer(nil, ""); !errors.Is(err, ErrProtocol) {
+		t.Errorf("StatServer after Disconnect returned %v\n", err)
+	}
+
+	if <-s.disconnects {
+		t.Errorf("Disconnect request had more than a header\n")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, user := range s.users {
+		if user != "tester" {
+			t.Errorf("Request made as %s\n", user)
+		}
+	}
+}
+
+func TestDISConnectFailure(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	_, err = DIS{Authenticate: func(net.Conn, string, int) error { return nil }}.Connect(addr)
+	if !errors.Is(err, ErrNoServer) {
+		t.Errorf("Connecting to closed port returned %v\n", err)
+	}
+}
@@ -0,0 +1,26 @@
+// Package pbs provides an interface to the C-based TORQUE library.
+// The functions present in this package are a thin wrapper around these
+// library functions and as such the TORQUE library provides documentation on
+// their usage.
+//
+// The TORQUE library is not thread safe, particulary when it comes to
+// reporting errors, and therefore problems *might* arise if you use this
+// package with goroutines. SetCallMode(Mutex) or SetCallMode(LockedThread)
+// serializes the calls, so that each error returned belongs to its own call.
+//
+// The package can be built without libtorque, and without cgo, by using the
+// nolibtorque build tag (or CGO_ENABLED=0). The Pbs_* functions for the
+// operations in Client are then implemented in Go with the DIS Backend, which
+// speaks TORQUE's batch protocol itself; the others, and the Torque Backend,
+// are unavailable.
+//
+// The following functions have not yet been implemented:
+/*
+   pbs_alterjob      - untested
+   pbs_alterjobasync
+   pbs_manager       - untested
+   pbs_rescreserve
+   pbs_asyncrunjob
+   pbs_sigjobasync
+*/
+package pbs
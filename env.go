@@ -0,0 +1,178 @@
+package pbs
+
+import (
+	"errors"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Env is a job's environment variables, as in Variable_List (ATTR_v)
+type Env map[string]string
+
+// ParseEnv parses a Variable_List, or the argument of qsub -v: a comma
+// separated list of NAME=value. A comma or backslash in a value is escaped
+// with a backslash, or the value can be quoted, e.g. A="x,y". A NAME
+// without a value has an empty value.
+func ParseEnv(s string) (Env, error) {
+	return ParseEnvLookup(s, nil)
+}
+
+// ParseEnvLookup is ParseEnv with the values of NAMEs without one from
+// lookup, as qsub -v takes them from its environment, e.g. with
+// os.LookupEnv. It should only be used for lists from a trusted source,
+// as it gives their author the values of any variable lookup has.
+func ParseEnvLookup(s string, lookup func(string) (string, bool)) (Env, error) {
+	env := Env{}
+	for s != "" {
+		var item string
+		var err error
+		item, s, err = nextEnvItem(s)
+		if err != nil {
+			return nil, err
+		}
+
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(item, "=")
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return nil, errors.New("pbs: missing variable name in " + strconv.Quote(item))
+		}
+		if !ok && lookup != nil {
+			value, _ = lookup(name)
+		}
+		env[name] = value
+	}
+	return env, nil
+}
+
+// nextEnvItem returns the first NAME=value of a Variable_List, with its
+// escapes and quotes removed, and the rest of the list
+func nextEnvItem(s string) (string, string, error) {
+	var b strings.Builder
+	var quote byte
+	value := -1
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\' && quote != '\'':
+			if i+1 == len(s) {
+				return "", "", errors.New("pbs: trailing backslash in variable list")
+			}
+			i++
+			b.WriteByte(s[i])
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else {
+				b.WriteByte(c)
+			}
+		case (c == '"' || c == '\'') && value == b.Len():
+			quote = c
+		case c == ',':
+			return b.String(), s[i+1:], nil
+		default:
+			b.WriteByte(c)
+			if c == '=' && value < 0 {
+				value = b.Len()
+			}
+		}
+	}
+	if quote != 0 {
+		return "", "", errors.New("pbs: unterminated quote in variable list")
+	}
+	return b.String(), "", nil
+}
+
+// String formats env as a Variable_List, in the order of the names, with
+// the commas and backslashes in values escaped
+func (env Env) String() string {
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	items := make([]string, len(names))
+	for i, name := range names {
+		value := strings.NewReplacer(`\`, `\\`, `,`, `\,`).Replace(env[name])
+		if strings.HasPrefix(value, `"`) || strings.HasPrefix(value, "'") {
+			// Quotes at the start of a value would be taken as quoting it
+			value = `\` + value
+		}
+		items[i] = name + "=" + value
+	}
+	return strings.Join(items, ",")
+}
+
+// Attrib returns env as the Variable_List attribute
+func (env Env) Attrib() Attrib {
+	return Attrib{Name: ATTR_v, Value: env.String()}
+}
+
+// Merge returns the variables of env and other, with other's taking
+// precedence
+func (env Env) Merge(other Env) Env {
+	merged := make(Env, len(env)+len(other))
+	for name, value := range env {
+		merged[name] = value
+	}
+	for name, value := range other {
+		merged[name] = value
+	}
+	return merged
+}
+
+// CaptureEnv returns the environment of the current process, as qsub -V
+// passes it to the job, with only the variables whose names match one of
+// the allow patterns, or all of them if there are none, and none matching
+// the deny patterns. Patterns are as for path.Match, e.g. "PBS_*".
+func CaptureEnv(allow []string, deny []string) (Env, error) {
+	for _, pattern := range append(append([]string(nil), allow...), deny...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, errors.New("pbs: bad environment pattern " + strconv.Quote(pattern))
+		}
+	}
+	matches := func(patterns []string, name string) bool {
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(pattern, name); ok {
+				return true
+			}
+		}
+		return false
+	}
+
+	env := Env{}
+	for _, item := range os.Environ() {
+		name, value, _ := strings.Cut(item, "=")
+		if name == "" || len(allow) > 0 && !matches(allow, name) || matches(deny, name) {
+			continue
+		}
+		env[name] = value
+	}
+	return env, nil
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (env Env) MarshalText() ([]byte, error) {
+	return []byte(env.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (env *Env) UnmarshalText(text []byte) error {
+	v, err := ParseEnv(string(text))
+	if err != nil {
+		return err
+	}
+	*env = v
+	return nil
+}
+
+// Env parses the job's Variable_List
+func (j Job) Env() (Env, error) {
+	return ParseEnv(j.Extra[ATTR_v])
+}
@@ -0,0 +1,85 @@
+package pbs
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestEnv(t *testing.T) {
+	t.Setenv("PBS_TEST_HOME", "/home/test")
+	list := `A=1,B="x,y",C=a\,b\\c,D='a b',PBS_TEST_HOME,E=,F=a=b`
+	env, err := ParseEnv(list)
+	if err != nil {
+		t.Fatalf("ParseEnv failed: %s\n", err)
+	}
+	expected := Env{"A": "1", "B": "x,y", "C": `a,b\c`, "D": "a b", "PBS_TEST_HOME": "", "E": "", "F": "a=b"}
+	if !reflect.DeepEqual(env, expected) {
+		t.Errorf("ParseEnv returned %q\n", env)
+	}
+	looked, err := ParseEnvLookup(list, os.LookupEnv)
+	if err != nil || looked["PBS_TEST_HOME"] != "/home/test" || looked["A"] != "1" {
+		t.Errorf("ParseEnvLookup returned %q, %v\n", looked, err)
+	}
+	env["PBS_TEST_HOME"] = "/home/test"
+
+	s := env.String()
+	if s != `A=1,B=x\,y,C=a\,b\\c,D=a b,E=,F=a=b,PBS_TEST_HOME=/home/test` {
+		t.Errorf("String returned %s\n", s)
+	}
+	quoted := Env{"Q": `"quoted",'x'`, "R": `mid"dle`}
+	for _, e := range []Env{env, quoted} {
+		if got, err := ParseEnv(e.String()); err != nil || !reflect.DeepEqual(got, e) {
+			t.Errorf("%q round tripped as %q, %v\n", e, got, err)
+		}
+	}
+
+	for _, bad := range []string{"=1", `A="x`, `A=x\`} {
+		if _, err := ParseEnv(bad); err == nil {
+			t.Errorf("ParseEnv(%q) didn't fail\n", bad)
+		}
+	}
+
+	merged := Env{"A": "1", "B": "2"}.Merge(Env{"B": "3"})
+	if merged.Attrib() != (Attrib{Name: ATTR_v, Value: "A=1,B=3"}) {
+		t.Errorf("Merge returned %q\n", merged)
+	}
+}
+
+func TestCaptureEnv(t *testing.T) {
+	t.Setenv("PBS_TEST_A", "1")
+	t.Setenv("PBS_TEST_B", "2")
+	t.Setenv("PBS_TEST_SECRET", "3")
+
+	env, err := CaptureEnv([]string{"PBS_TEST_*"}, []string{"*SECRET"})
+	if err != nil {
+		t.Fatalf("CaptureEnv failed: %s\n", err)
+	}
+	if !reflect.DeepEqual(env, Env{"PBS_TEST_A": "1", "PBS_TEST_B": "2"}) {
+		t.Errorf("CaptureEnv returned %q\n", env)
+	}
+	if env, _ := CaptureEnv(nil, nil); env["PBS_TEST_SECRET"] != "3" {
+		t.Errorf("CaptureEnv without patterns didn't return every variable\n")
+	}
+	if _, err := CaptureEnv([]string{"["}, nil); err == nil {
+		t.Errorf("CaptureEnv with a bad pattern didn't fail\n")
+	}
+}
+
+func TestJobEnv(t *testing.T) {
+	server := NewFakeServer("fake")
+	client := connectFake(t, server)
+
+	env := Env{"PATH": "/bin", "LIST": "a,b"}
+	if _, err := client.Submit([]Attrib{env.Attrib()}, "job.sh", "", ""); err != nil {
+		t.Fatalf("Submit failed: %s\n", err)
+	}
+	jobs, err := StatJobs(client, "", "")
+	if err != nil || len(jobs) != 1 {
+		t.Fatalf("StatJobs returned %v, %v\n", jobs, err)
+	}
+	got, err := jobs[0].Env()
+	if err != nil || !reflect.DeepEqual(got, env) {
+		t.Errorf("Env returned %q, %v\n", got, err)
+	}
+}
@@ -0,0 +1,248 @@
+package pbs
+
+import (
+	"fmt"
+)
+
+// Error numbers reported by the TORQUE library in pbs_errno and by the
+// server in batch replies, as defined in torque/pbs_error.h
+const (
+	PBSE_NONE       = 0
+	PBSE_UNKJOBID   = 15001 // Unknown Job Identifier
+	PBSE_NOATTR     = 15002 // Undefined Attribute
+	PBSE_ATTRRO     = 15003 // attempt to set READ ONLY attribute
+	PBSE_IVALREQ    = 15004 // Invalid request
+	PBSE_UNKREQ     = 15005 // Unknown batch request
+	PBSE_TOOMANY    = 15006 // Too many submit retries
+	PBSE_PERM       = 15007 // No permission
+	PBSE_BADHOST    = 15008 // access from host not allowed
+	PBSE_JOBEXIST   = 15009 // job already exists
+	PBSE_SYSTEM     = 15010 // system error occurred
+	PBSE_INTERNAL   = 15011 // internal server error occurred
+	PBSE_REGROUTE   = 15012 // parent job of dependent in rte que
+	PBSE_UNKSIG     = 15013 // unknown signal name
+	PBSE_BADATVAL   = 15014 // bad attribute value
+	PBSE_MODATRRUN  = 15015 // Cannot modify attrib in run state
+	PBSE_BADSTATE   = 15016 // request invalid for job state
+	PBSE_UNKQUE     = 15018 // Unknown queue name
+	PBSE_BADCRED    = 15019 // Invalid Credential in request
+	PBSE_EXPIRED    = 15020 // Expired Credential in request
+	PBSE_QUNOENB    = 15021 // Queue not enabled
+	PBSE_QACESS     = 15022 // No access permission for queue
+	PBSE_BADUSER    = 15023 // Bad user - no password entry
+	PBSE_HOPCOUNT   = 15024 // Max hop count exceeded
+	PBSE_QUEEXIST   = 15025 // Queue already exists
+	PBSE_ATTRTYPE   = 15026 // incompatable queue attribute type
+	PBSE_QUEBUSY    = 15027 // Queue Busy (not empty)
+	PBSE_QUENBIG    = 15028 // Queue name too long
+	PBSE_NOSUP      = 15029 // Feature/function not supported
+	PBSE_QUENOEN    = 15030 // Cannot enable queue, needs add def
+	PBSE_PROTOCOL   = 15031 // Protocol (ASN.1) error
+	PBSE_BADATLST   = 15032 // Bad attribute list structure
+	PBSE_NOCONNECTS = 15033 // No free connections
+	PBSE_NOSERVER   = 15034 // No server to connect to
+	PBSE_UNKRESC    = 15035 // Unknown resource
+	PBSE_EXCQRESC   = 15036 // Job exceeds Queue resource limits
+	PBSE_QUENODFLT  = 15037 // No Default Queue Defined
+	PBSE_NORERUN    = 15038 // Job Not Rerunnable
+	PBSE_ROUTEREJ   = 15039 // Route rejected by all destinations
+	PBSE_ROUTEEXPD  = 15040 // Time in Route Queue Expired
+	PBSE_MOMREJECT  = 15041 // Request to MOM failed
+	PBSE_BADSCRIPT  = 15042 // (qsub) cannot access script file
+	PBSE_STAGEIN    = 15043 // Stage In of files failed
+	PBSE_RESCUNAV   = 15044 // Resources temporarily unavailable
+	PBSE_BADGRP     = 15045 // Bad Group specified
+	PBSE_MAXQUED    = 15046 // Max number of jobs in queue
+	PBSE_CKPBSY     = 15047 // Checkpoint Busy, may be retries
+	PBSE_EXLIMIT    = 15048 // Limit exceeds allowable
+	PBSE_BADACCT    = 15049 // Bad Account attribute value
+	PBSE_ALRDYEXIT  = 15050 // Job already in exit state
+	PBSE_NOCOPYFILE = 15051 // Job files not copied
+	PBSE_CLEANEDOUT = 15052 // unknown job id after clean init
+	PBSE_NOSYNCMSTR = 15053 // No Master in Sync Set
+	PBSE_BADDEPEND  = 15054 // Invalid dependency
+	PBSE_DUPLIST    = 15055 // Duplicate entry in List
+	PBSE_DISPROTO   = 15056 // Bad DIS based Request Protocol
+	PBSE_EXECTHERE  = 15057 // cannot execute there
+	PBSE_SISREJECT  = 15058 // sister rejected
+	PBSE_SISCOMM    = 15059 // sister could not communicate
+	PBSE_SVRDOWN    = 15060 // req rejected -server shutting down
+	PBSE_CKPSHORT   = 15061 // not all tasks could checkpoint
+	PBSE_UNKNODE    = 15062 // Named node is not in the list
+	PBSE_UNKNODEATR = 15063 // node-attribute not recognized
+	PBSE_NONODES    = 15064 // Server has no node list
+	PBSE_NODENBIG   = 15065 // Node name is too big
+	PBSE_NODEEXIST  = 15066 // Node name already exists
+	PBSE_BADNDATVAL = 15067 // Bad node-attribute value
+	PBSE_MUTUALEX   = 15068 // State values are mutually exclusive
+	PBSE_GMODERR    = 15069 // Error(s) during global modification of nodes
+	PBSE_NORELYMOM  = 15070 // could not contact Mom
+	PBSE_NOTSNODE   = 15071 // no time-shared nodes
+)
+
+// errorText mirrors the messages returned by pbs_strerror() so that errors
+// can be described without a call into the library
+var errorText = map[int]string{
+	PBSE_NONE:       "No error",
+	PBSE_UNKJOBID:   "Unknown Job Id",
+	PBSE_NOATTR:     "Undefined attribute",
+	PBSE_ATTRRO:     "Cannot set attribute, read only or insufficient permission",
+	PBSE_IVALREQ:    "Invalid request",
+	PBSE_UNKREQ:     "Unknown request",
+	PBSE_TOOMANY:    "Too many submit retries",
+	PBSE_PERM:       "Unauthorized Request",
+	PBSE_BADHOST:    "Access from host not allowed",
+	PBSE_JOBEXIST:   "Job with requested ID already exists",
+	PBSE_SYSTEM:     "System error",
+	PBSE_INTERNAL:   "PBS server internal error",
+	PBSE_REGROUTE:   "Dependent parent job currently in routing queue",
+	PBSE_UNKSIG:     "Unknown/illegal signal name",
+	PBSE_BADATVAL:   "Illegal attribute or resource value",
+	PBSE_MODATRRUN:  "Cannot modify attribute while job running",
+	PBSE_BADSTATE:   "Request invalid for state of job",
+	PBSE_UNKQUE:     "Unknown queue",
+	PBSE_BADCRED:    "Invalid credential",
+	PBSE_EXPIRED:    "Expired credential",
+	PBSE_QUNOENB:    "Queue is not enabled",
+	PBSE_QACESS:     "Access to queue is denied",
+	PBSE_BADUSER:    "Bad UID for job execution",
+	PBSE_HOPCOUNT:   "Job routing over too many hops",
+	PBSE_QUEEXIST:   "Queue already exists",
+	PBSE_ATTRTYPE:   "Incompatible type",
+	PBSE_QUEBUSY:    "Cannot delete busy queue",
+	PBSE_QUENBIG:    "Queue name too long",
+	PBSE_NOSUP:      "No support for requested service",
+	PBSE_QUENOEN:    "Cannot enable queue, incomplete definition",
+	PBSE_PROTOCOL:   "Batch protocol error",
+	PBSE_BADATLST:   "Bad attribute list structure",
+	PBSE_NOCONNECTS: "No free connections",
+	PBSE_NOSERVER:   "No server specified",
+	PBSE_UNKRESC:    "Unknown resource type",
+	PBSE_EXCQRESC:   "Job exceeds queue resource limits",
+	PBSE_QUENODFLT:  "No default queue specified",
+	PBSE_NORERUN:    "Job is not rerunnable",
+	PBSE_ROUTEREJ:   "Job rejected by all possible destinations",
+	PBSE_ROUTEEXPD:  "Time in Route Queue Expired",
+	PBSE_MOMREJECT:  "Execution server rejected request",
+	PBSE_BADSCRIPT:  "(qsub) cannot access script file",
+	PBSE_STAGEIN:    "Stage-in of files failed",
+	PBSE_RESCUNAV:   "Resource temporarily unavailable",
+	PBSE_BADGRP:     "Bad GID for job execution",
+	PBSE_MAXQUED:    "Maximum number of jobs already in queue",
+	PBSE_CKPBSY:     "Checkpoint busy, may retry",
+	PBSE_EXLIMIT:    "Resource limit exceeds allowable",
+	PBSE_BADACCT:    "Invalid Account",
+	PBSE_ALRDYEXIT:  "Job already in exit state",
+	PBSE_NOCOPYFILE: "Job files not copied",
+	PBSE_CLEANEDOUT: "Unknown job id after clean init",
+	PBSE_NOSYNCMSTR: "No master found for sync job set",
+	PBSE_BADDEPEND:  "Invalid dependency",
+	PBSE_DUPLIST:    "Duplicate entry in list",
+	PBSE_DISPROTO:   "Bad DIS based Request Protocol",
+	PBSE_EXECTHERE:  "Cannot execute at specified host because of checkpoint or stagein files",
+	PBSE_SISREJECT:  "Sister rejected",
+	PBSE_SISCOMM:    "Sister could not communicate",
+	PBSE_SVRDOWN:    "Request not allowed: Server shutting down",
+	PBSE_CKPSHORT:   "Not all tasks could checkpoint",
+	PBSE_UNKNODE:    "Unknown node",
+	PBSE_UNKNODEATR: "Unknown node-attribute",
+	PBSE_NONODES:    "Server has no node list",
+	PBSE_NODENBIG:   "Node name is too big",
+	PBSE_NODEEXIST:  "Node name already exists",
+	PBSE_BADNDATVAL: "Illegal value for node attribute",
+	PBSE_MUTUALEX:   "Mutually exclusive values for node attribute",
+	PBSE_GMODERR:    "Modification failed for node",
+	PBSE_NORELYMOM:  "Server could not connect to MOM",
+	PBSE_NOTSNODE:   "No time-share node available",
+}
+
+// PBSError is the error returned when a call to the server fails. Errno is
+// the value of pbs_errno after the call, Msg is its description from
+// Pbs_strerror and ServerMsg is the message the server returned, as reported
+// by Pbs_geterrmsg. Err is the underlying cause when the failure didn't come
+// from the server, such as a network error.
+type PBSError struct {
+	Op        string // the library function which failed, e.g. "pbs_submit"
+	ID        string // the job or object the operation was applied to, if any
+	Errno     int
+	Msg       string
+	ServerMsg string
+	Err       error
+}
+
+func (e *PBSError) Error() string {
+	s := e.Op
+	if e.ID != "" {
+		s += " " + e.ID
+	}
+	if s != "" {
+		s += ": "
+	}
+
+	msg := e.Msg
+	if msg == "" {
+		msg = errorText[e.Errno]
+	}
+	if msg == "" {
+		msg = fmt.Sprintf("error %d", e.Errno)
+	}
+	s += msg
+
+	if e.ServerMsg != "" && e.ServerMsg != msg {
+		s += " (" + e.ServerMsg + ")"
+	}
+	if e.Err != nil {
+		s += ": " + e.Err.Error()
+	}
+	return s
+}
+
+// Unwrap returns the underlying cause of the error, if any
+func (e *PBSError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is a *PBSError with the same Errno, so that the
+// sentinel errors can be used with errors.Is
+func (e *PBSError) Is(target error) bool {
+	t, ok := target.(*PBSError)
+	return ok && t.Errno == e.Errno
+}
+
+func errnoError(errno int) *PBSError {
+	return &PBSError{Errno: errno, Msg: errorText[errno]}
+}
+
+// Sentinel errors for the common failures, for use with errors.Is:
+//
+//	if errors.Is(err, pbs.ErrUnknownJob) {
+//		...
+//	}
+var (
+	ErrUnknownJob      = errnoError(PBSE_UNKJOBID)
+	ErrNoAttr          = errnoError(PBSE_NOATTR)
+	ErrReadOnly        = errnoError(PBSE_ATTRRO)
+	ErrInvalidRequest  = errnoError(PBSE_IVALREQ)
+	ErrPermission      = errnoError(PBSE_PERM)
+	ErrBadHost         = errnoError(PBSE_BADHOST)
+	ErrJobExists       = errnoError(PBSE_JOBEXIST)
+	ErrSystem          = errnoError(PBSE_SYSTEM)
+	ErrInternal        = errnoError(PBSE_INTERNAL)
+	ErrUnknownSignal   = errnoError(PBSE_UNKSIG)
+	ErrBadAttrValue    = errnoError(PBSE_BADATVAL)
+	ErrBadState        = errnoError(PBSE_BADSTATE)
+	ErrUnknownQueue    = errnoError(PBSE_UNKQUE)
+	ErrBadCredential   = errnoError(PBSE_BADCRED)
+	ErrQueueExists     = errnoError(PBSE_QUEEXIST)
+	ErrQueueBusy       = errnoError(PBSE_QUEBUSY)
+	ErrNotSupported    = errnoError(PBSE_NOSUP)
+	ErrProtocol        = errnoError(PBSE_PROTOCOL)
+	ErrNoConnects      = errnoError(PBSE_NOCONNECTS)
+	ErrNoServer        = errnoError(PBSE_NOSERVER)
+	ErrUnknownResource = errnoError(PBSE_UNKRESC)
+	ErrBadScript       = errnoError(PBSE_BADSCRIPT)
+	ErrBadDepend       = errnoError(PBSE_BADDEPEND)
+	ErrDISProtocol     = errnoError(PBSE_DISPROTO)
+	ErrServerDown      = errnoError(PBSE_SVRDOWN)
+	ErrUnknownNode     = errnoError(PBSE_UNKNODE)
+)
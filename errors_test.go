@@ -0,0 +1,62 @@
+package pbs
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestPBSErrorIs(t *testing.T) {
+	err := fmt.Errorf("deleting: %w", &PBSError{
+		Op:        "pbs_deljob",
+		ID:        "1.localhost",
+		Errno:     PBSE_UNKJOBID,
+		Msg:       "Unknown Job Id",
+		ServerMsg: "Unknown Job Id 1.localhost",
+	})
+
+	if !errors.Is(err, ErrUnknownJob) {
+		t.Errorf("%s is not ErrUnknownJob", err)
+	}
+	if errors.Is(err, ErrPermission) {
+		t.Errorf("%s is ErrPermission", err)
+	}
+
+	var pe *PBSError
+	if !errors.As(err, &pe) {
+		t.Fatalf("%s is not a *PBSError", err)
+	}
+	if pe.Errno != PBSE_UNKJOBID || pe.ID != "1.localhost" {
+		t.Errorf("unexpected error fields: %+v", pe)
+	}
+}
+
+func TestPBSErrorString(t *testing.T) {
+	tests := []struct {
+		err  *PBSError
+		want string
+	}{
+		{
+			&PBSError{Op: "pbs_deljob", ID: "1.localhost", Errno: PBSE_UNKJOBID, Msg: "Unknown Job Id"},
+			"pbs_deljob 1.localhost: Unknown Job Id",
+		},
+		{
+			&PBSError{Op: "pbs_submit", Errno: PBSE_PERM, ServerMsg: "qsub not allowed"},
+			"pbs_submit: Unauthorized Request (qsub not allowed)",
+		},
+		{
+			&PBSError{Errno: 99999},
+			"error 99999",
+		},
+		{
+			ErrProtocol,
+			"Batch protocol error",
+		},
+	}
+
+	for _, test := range tests {
+		if got := test.err.Error(); got != test.want {
+			t.Errorf("got %q, want %q", got, test.want)
+		}
+	}
+}
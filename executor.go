@@ -0,0 +1,92 @@
+package pbs
+
+import (
+	"runtime"
+	"sync"
+)
+
+// CallMode controls how the Pbs_* functions make their calls into
+// libtorque, which isn't safe for concurrent use: pbs_errno and the
+// connection table are global, so an error read after a failed call may
+// belong to a call made from another goroutine.
+type CallMode int
+
+const (
+	// Unserialized makes calls directly from the calling goroutine. This is
+	// the default, and is fine for programs which use the package from one
+	// goroutine at a time.
+	Unserialized CallMode = iota
+
+	// Mutex makes calls, and reads their errors, holding a global lock
+	Mutex
+
+	// LockedThread makes every call, and reads its error, from a single
+	// goroutine locked to its OS thread, for when the library keeps state
+	// per thread as well
+	LockedThread
+)
+
+// SetCallMode sets how calls into libtorque are made, waiting for calls in
+// progress to finish. Without libtorque it has no effect, as the DIS
+// Backend is safe for concurrent use.
+func SetCallMode(mode CallMode) {
+	libtorque.setMode(mode)
+}
+
+// libtorque is the executor for the calls made by the Pbs_* functions
+var libtorque = &executor{}
+
+// executor runs functions according to its CallMode
+type executor struct {
+	// mu is held for reading by calls in progress, so that the mode only
+	// changes between them
+	mu    sync.RWMutex
+	mode  CallMode
+	call  sync.Mutex
+	calls chan func()
+}
+
+func (x *executor) do(f func()) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	switch x.mode {
+	case Mutex:
+		x.call.Lock()
+		defer x.call.Unlock()
+		f()
+	case LockedThread:
+		done := make(chan struct{})
+		x.calls <- func() {
+			defer close(done)
+			f()
+		}
+		<-done
+	default:
+		f()
+	}
+}
+
+func (x *executor) setMode(mode CallMode) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if mode == LockedThread && x.calls == nil {
+		x.calls = make(chan func())
+		go lockedThread(x.calls)
+	} else if mode != LockedThread && x.calls != nil {
+		close(x.calls)
+		x.calls = nil
+	}
+	x.mode = mode
+}
+
+// lockedThread runs calls on the current OS thread until the channel is
+// closed
+func lockedThread(calls <-chan func()) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	for f := range calls {
+		f()
+	}
+}
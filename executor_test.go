@@ -0,0 +1,85 @@
+package pbs
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+// fakeLibrary imitates libtorque's error reporting: a failed call sets a
+// global errno, which is read by a separate call afterwards
+type fakeLibrary struct {
+	errno int
+}
+
+func (l *fakeLibrary) fail(errno int) int {
+	l.errno = errno
+	// Give other goroutines the chance to make calls before errno is read
+	runtime.Gosched()
+	return -1
+}
+
+// submit is a wrapper in the style of the Pbs_* functions
+func (l *fakeLibrary) submit(x *executor, errno int) error {
+	var err error
+	x.do(func() {
+		if l.fail(errno) != 0 {
+			err = errnoError(l.errno)
+		}
+	})
+	return err
+}
+
+func TestExecutor(t *testing.T) {
+	for _, mode := range []CallMode{Mutex, LockedThread} {
+		x := &executor{}
+		x.setMode(mode)
+		lib := &fakeLibrary{}
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 50)
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func(errno int) {
+				defer wg.Done()
+				for j := 0; j < 100; j++ {
+					err := lib.submit(x, errno)
+					if pe, ok := err.(*PBSError); !ok || pe.Errno != errno {
+						errs <- fmt.Errorf("call failing with %d returned %v", errno, err)
+						return
+					}
+				}
+			}(PBSE_UNKJOBID + i)
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			t.Errorf("Mode %d: %s\n", mode, err)
+		}
+		x.setMode(Unserialized)
+	}
+}
+
+func TestExecutorModeChange(t *testing.T) {
+	x := &executor{}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if i == 0 {
+					x.setMode(CallMode(j % 3))
+				} else {
+					x.do(func() {})
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	x.setMode(Unserialized)
+	if x.calls != nil {
+		t.Errorf("Locked thread still running after leaving LockedThread mode\n")
+	}
+}
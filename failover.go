@@ -0,0 +1,294 @@
+package pbs
+
+import (
+	"strings"
+	"sync"
+)
+
+// ParseServerList splits a comma separated list of servers, as returned by
+// Pbs_get_server_list, into its names
+func ParseServerList(list string) []string {
+	var servers []string
+	for _, s := range strings.Split(list, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			servers = append(servers, s)
+		}
+	}
+	return servers
+}
+
+// ServerList returns the configured servers, from Pbs_get_server_list
+// followed by Pbs_fbserver, without duplicates
+func ServerList() []string {
+	servers := ParseServerList(Pbs_get_server_list())
+	if fb := strings.TrimSpace(Pbs_fbserver()); fb != "" {
+		for _, s := range servers {
+			if s == fb {
+				return servers
+			}
+		}
+		servers = append(servers, fb)
+	}
+	return servers
+}
+
+// Failover is a Backend which connects to the first server in a list that
+// accepts a connection, starting from the active server: the one last
+// connected to. Its Clients move on to the next server, and retry the
+// request once, when a request fails with a connection error (see
+// IsConnError). Requests which can't safely be repeated, such as Submit,
+// may then have been made twice.
+type Failover struct {
+	// Backend makes the connections, DefaultBackend if nil
+	Backend Backend
+
+	// Servers in order of preference. If empty the server given to Connect
+	// is used as a comma separated list, and if that is empty the list from
+	// ServerList.
+	Servers []string
+
+	// OnFailover, if set, is called when a connection is made to a server
+	// other than the active one, or the first in the list if there isn't
+	// one yet, with the error which caused the change
+	OnFailover func(from string, to string, err error)
+
+	mu     sync.Mutex
+	active string
+}
+
+var _ Backend = (*Failover)(nil)
+
+// Active returns the server last connected to
+func (f *Failover) Active() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.active
+}
+
+func (f *Failover) servers(server string) []string {
+	if len(f.Servers) > 0 {
+		return f.Servers
+	}
+	if server != "" {
+		return ParseServerList(server)
+	}
+	return ServerList()
+}
+
+// Connect connects to the active server, or failing that the others in
+// order
+func (f *Failover) Connect(server string) (Client, error) {
+	servers := f.servers(server)
+	if len(servers) == 0 {
+		return nil, &PBSError{Op: "pbs_connect", Errno: PBSE_NOSERVER, Msg: errorText[PBSE_NOSERVER]}
+	}
+
+	client, name, err := f.connect(servers, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	return &failoverClient{failover: f, servers: servers, client: client, server: name}, nil
+}
+
+// connect tries servers in turn, starting with the active one or, if
+// failed is set, the one after it. cause is the error which made the
+// connection necessary, if any.
+func (f *Failover) connect(servers []string, failed string, cause error) (Client, string, error) {
+	backend := f.Backend
+	if backend == nil {
+		backend = DefaultBackend
+	}
+
+	f.mu.Lock()
+	from := f.active
+	f.mu.Unlock()
+	if from == "" {
+		from = servers[0]
+	}
+
+	start := 0
+	for i, s := range servers {
+		if failed != "" && s == failed {
+			start = i + 1
+			break
+		}
+		if failed == "" && s == from {
+			start = i
+		}
+	}
+
+	var err error
+	for i := range servers {
+		server := servers[(start+i)%len(servers)]
+		var client Client
+		client, err = backend.Connect(server)
+		if err != nil {
+			cause = err
+			continue
+		}
+
+		f.mu.Lock()
+		f.active = server
+		f.mu.Unlock()
+		if server != from && f.OnFailover != nil {
+			f.OnFailover(from, server, cause)
+		}
+		return client, server, nil
+	}
+	return nil, "", err
+}
+
+// failoverClient is a Client from a Failover, which reconnects when its
+// connection fails
+type failoverClient struct {
+	failover *Failover
+	servers  []string
+
+	mu     sync.Mutex
+	client Client
+	server string
+	closed bool
+}
+
+var _ Client = (*failoverClient)(nil)
+
+// failoverCall makes a request with c's connection, reconnecting and trying
+// again if it fails with a connection error
+func failoverCall[T any](c *failoverClient, f func(Client) (T, error)) (T, error) {
+	c.mu.Lock()
+	client, closed := c.client, c.closed
+	c.mu.Unlock()
+
+	v, err := f(client)
+	if closed || !IsConnError(err) {
+		return v, err
+	}
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return v, err
+	}
+	// Another request may already have replaced the connection
+	if c.client == client {
+		client.Disconnect()
+		next, server, cerr := c.failover.connect(c.servers, c.server, err)
+		if cerr != nil {
+			c.mu.Unlock()
+			return v, err
+		}
+		c.client, c.server = next, server
+	}
+	client = c.client
+	c.mu.Unlock()
+
+	return f(client)
+}
+
+func (c *failoverClient) Submit(attribs []Attrib, script string, destination string, extend string) (string, error) {
+	return failoverCall(c, func(client Client) (string, error) {
+		return client.Submit(attribs, script, destination, extend)
+	})
+}
+
+func (c *failoverClient) SubmitScript(attribs []Attrib, script []byte, destination string, extend string) (string, error) {
+	return failoverCall(c, func(client Client) (string, error) {
+		return SubmitBytes(client, attribs, script, destination, extend)
+	})
+}
+
+func (c *failoverClient) StatJob(id string, attribs []Attrib, extend string) ([]BatchStatus, error) {
+	return failoverCall(c, func(client Client) ([]BatchStatus, error) {
+		return client.StatJob(id, attribs, extend)
+	})
+}
+
+func (c *failoverClient) StatNode(id string, attribs []Attrib, extend string) ([]BatchStatus, error) {
+	return failoverCall(c, func(client Client) ([]BatchStatus, error) {
+		return client.StatNode(id, attribs, extend)
+	})
+}
+
+func (c *failoverClient) StatQue(id string, attribs []Attrib, extend string) ([]BatchStatus, error) {
+	return failoverCall(c, func(client Client) ([]BatchStatus, error) {
+		return client.StatQue(id, attribs, extend)
+	})
+}
+
+func (c *failoverClient) StatServer(attribs []Attrib, extend string) ([]BatchStatus, error) {
+	return failoverCall(c, func(client Client) ([]BatchStatus, error) {
+		return client.StatServer(attribs, extend)
+	})
+}
+
+func (c *failoverClient) SelectJob(attribs []Attrib, extend string) ([]string, error) {
+	return failoverCall(c, func(client Client) ([]string, error) {
+		return client.SelectJob(attribs, extend)
+	})
+}
+
+func (c *failoverClient) SelStat(attribs []Attrib, extend string) ([]BatchStatus, error) {
+	return failoverCall(c, func(client Client) ([]BatchStatus, error) {
+		return client.SelStat(attribs, extend)
+	})
+}
+
+func (c *failoverClient) HoldJob(id string, holdType Hold, extend string) error {
+	_, err := failoverCall(c, func(client Client) (struct{}, error) {
+		return struct{}{}, client.HoldJob(id, holdType, extend)
+	})
+	return err
+}
+
+func (c *failoverClient) RlsJob(id string, holdType Hold, extend string) error {
+	_, err := failoverCall(c, func(client Client) (struct{}, error) {
+		return struct{}{}, client.RlsJob(id, holdType, extend)
+	})
+	return err
+}
+
+func (c *failoverClient) DelJob(id string, extend string) error {
+	_, err := failoverCall(c, func(client Client) (struct{}, error) {
+		return struct{}{}, client.DelJob(id, extend)
+	})
+	return err
+}
+
+func (c *failoverClient) AlterJob(id string, attribs []Attrib, extend string) error {
+	_, err := failoverCall(c, func(client Client) (struct{}, error) {
+		return struct{}{}, client.AlterJob(id, attribs, extend)
+	})
+	return err
+}
+
+func (c *failoverClient) MoveJob(id string, destination string, extend string) error {
+	_, err := failoverCall(c, func(client Client) (struct{}, error) {
+		return struct{}{}, client.MoveJob(id, destination, extend)
+	})
+	return err
+}
+
+func (c *failoverClient) SigJob(id string, signal string, extend string) error {
+	_, err := failoverCall(c, func(client Client) (struct{}, error) {
+		return struct{}{}, client.SigJob(id, signal, extend)
+	})
+	return err
+}
+
+func (c *failoverClient) Manager(command Command, objType ObjectType, name string, attribs []Attrib, extend string) error {
+	_, err := failoverCall(c, func(client Client) (struct{}, error) {
+		return struct{}{}, client.Manager(command, objType, name, attribs, extend)
+	})
+	return err
+}
+
+func (c *failoverClient) Disconnect() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return closedError("pbs_disconnect", "")
+	}
+	c.closed = true
+	return c.client.Disconnect()
+}
@@ -0,0 +1,163 @@
+package pbs
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// flakyCluster is a Backend for several FakeServers which can be taken
+// down. Connections to a server which is down fail, as do StatServer
+// requests on connections made before it went down.
+type flakyCluster struct {
+	mu      sync.Mutex
+	servers map[string]*FakeServer
+	down    map[string]bool
+}
+
+func newFlakyCluster(names ...string) *flakyCluster {
+	c := &flakyCluster{servers: map[string]*FakeServer{}, down: map[string]bool{}}
+	for _, name := range names {
+		c.servers[name] = NewFakeServer(name)
+	}
+	return c
+}
+
+func (c *flakyCluster) setDown(name string, down bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.down[name] = down
+}
+
+func (c *flakyCluster) isDown(name string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.down[name]
+}
+
+func (c *flakyCluster) Connect(server string) (Client, error) {
+	if c.isDown(server) {
+		return nil, &PBSError{Op: "pbs_connect", ID: server, Errno: PBSE_NOSERVER}
+	}
+	client, err := c.servers[server].Connect(server)
+	if err != nil {
+		return nil, err
+	}
+	return &flakyClient{Client: client, cluster: c, server: server}, nil
+}
+
+type flakyClient struct {
+	Client
+	cluster *flakyCluster
+	server  string
+}
+
+func (c *flakyClient) StatServer(attribs []Attrib, extend string) ([]BatchStatus, error) {
+	if c.cluster.isDown(c.server) {
+		return nil, &PBSError{Op: "pbs_statserver", Errno: PBSE_PROTOCOL}
+	}
+	return c.Client.StatServer(attribs, extend)
+}
+
+type failoverEvent struct {
+	from, to string
+}
+
+func TestFailover(t *testing.T) {
+	cluster := newFlakyCluster("primary", "secondary")
+	var events []failoverEvent
+	f := &Failover{
+		Backend: cluster,
+		Servers: []string{"primary", "secondary"},
+		OnFailover: func(from string, to string, err error) {
+			if !IsConnError(err) {
+				t.Errorf("Failover from %s to %s reported with %v\n", from, to, err)
+			}
+			events = append(events, failoverEvent{from, to})
+		},
+	}
+
+	client, err := f.Connect("")
+	if err != nil {
+		t.Fatalf("Connect failed: %s\n", err)
+	}
+	if f.Active() != "primary" || len(events) != 0 {
+		t.Errorf("Active server %q after %v\n", f.Active(), events)
+	}
+
+	// The connection moves to the secondary when the primary fails
+	cluster.setDown("primary", true)
+	batch, err := client.StatServer(nil, "")
+	if err != nil || len(batch) != 1 || batch[0].Name != "secondary" {
+		t.Errorf("StatServer after failover returned %v, %v\n", batch, err)
+	}
+	if f.Active() != "secondary" {
+		t.Errorf("Active server is %q\n", f.Active())
+	}
+
+	// New connections go to the active server, even once the primary is
+	// back
+	cluster.setDown("primary", false)
+	other, err := f.Connect("")
+	if err != nil {
+		t.Fatalf("Connect failed: %s\n", err)
+	}
+	if batch, err := other.StatServer(nil, ""); err != nil || batch[0].Name != "secondary" {
+		t.Errorf("New connection went to %v, %v\n", batch, err)
+	}
+
+	cluster.setDown("secondary", true)
+	if batch, err := other.StatServer(nil, ""); err != nil || batch[0].Name != "primary" {
+		t.Errorf("Second failover went to %v, %v\n", batch, err)
+	}
+
+	expected := []failoverEvent{{"primary", "secondary"}, {"secondary", "primary"}}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("Failovers reported %v, expected %v\n", events, expected)
+	}
+
+	// With everything down the error is returned
+	cluster.setDown("primary", true)
+	if _, err := other.StatServer(nil, ""); !errors.Is(err, ErrProtocol) {
+		t.Errorf("StatServer with all servers down returned %v\n", err)
+	}
+	if _, err := f.Connect(""); !errors.Is(err, ErrNoServer) {
+		t.Errorf("Connect with all servers down returned %v\n", err)
+	}
+
+	client.Disconnect()
+	other.Disconnect()
+}
+
+func TestFailoverConnect(t *testing.T) {
+	cluster := newFlakyCluster("a", "b", "c")
+	cluster.setDown("a", true)
+	cluster.setDown("b", true)
+
+	var from, to string
+	f := &Failover{Backend: cluster, OnFailover: func(f string, t string, err error) { from, to = f, t }}
+	client, err := f.Connect("a, b,c")
+	if err != nil {
+		t.Fatalf("Connect failed: %s\n", err)
+	}
+	defer client.Disconnect()
+	if f.Active() != "c" || from != "a" || to != "c" {
+		t.Errorf("Active server %q, failover reported from %q to %q\n", f.Active(), from, to)
+	}
+}
+
+func TestParseServerList(t *testing.T) {
+	tests := map[string][]string{
+		"":                         nil,
+		"torque":                   {"torque"},
+		"a,b:15001":                {"a", "b:15001"},
+		" a , ,b ":                 {"a", "b"},
+		"host1.example.com,host2,": {"host1.example.com", "host2"},
+	}
+	for list, expected := range tests {
+		if servers := ParseServerList(list); !reflect.DeepEqual(servers, expected) {
+			t.Errorf("ParseServerList(%q) returned %q, expected %q\n", list, servers, expected)
+		}
+	}
+}
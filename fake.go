@@ -0,0 +1,1299 @@
+package pbs
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FakeServer is an in-memory stand-in for pbs_server which can be used as a
+// Backend in tests, so that code written against Client can be run without
+// a TORQUE installation.
+//
+// Jobs move from Q to R to C as the server's clock is moved forward with
+// Advance: queued jobs are started on nodes with free slots (or
+// immediately, if no nodes have been added) and complete once they have run
+// for the runtime set with SetRuntime. Job arrays, submitted with ATTR_t,
+// have a subjob for each index and keep to their slot limit. Errors are
+// *PBSError values with the same errno as the real server would return, and
+// the status calls return BatchStatus values with the attributes pbs_server
+// reports.
+type FakeServer struct {
+	mu           sync.Mutex
+	name         string
+	now          time.Time
+	runtime      time.Duration
+	seq          int
+	attribs      []Attrib
+	queues       map[string]*fakeQueue
+	queueOrder   []string
+	nodes        map[string]*fakeNode
+	nodeOrder    []string
+	jobs         map[string]*fakeJob
+	jobOrder     []string
+	defaultQueue string
+	scheduling   bool
+}
+
+type fakeQueue struct {
+	name    string
+	enabled bool
+	started bool
+	attribs []Attrib
+}
+
+type fakeNode struct {
+	name       string
+	np         int
+	properties []string
+	offline    bool
+	slots      []string
+	attribs    []Attrib
+}
+
+type fakeJob struct {
+	id         string
+	key        string
+	seq        int
+	name       string
+	owner      string
+	queue      string
+	script     string
+	contents   []byte
+	state      JobState
+	holds      string
+	attribs    []Attrib
+	ctime      time.Time
+	mtime      time.Time
+	qtime      time.Time
+	start      time.Time
+	finish     time.Time
+	execHost   string
+	exitStatus int
+
+	// Job arrays have subjobs, which have a parent and an index
+	subjobs []*fakeJob
+	limit   int
+	parent  *fakeJob
+	index   int
+}
+
+// NewFakeServer returns a FakeServer called name with a single queue,
+// "batch", which is the default queue. The clock starts at the current
+// time and jobs run for one minute.
+func NewFakeServer(name string) *FakeServer {
+	s := &FakeServer{
+		name:         name,
+		now:          time.Now().Truncate(time.Second),
+		runtime:      time.Minute,
+		queues:       map[string]*fakeQueue{},
+		nodes:        map[string]*fakeNode{},
+		jobs:         map[string]*fakeJob{},
+		defaultQueue: "batch",
+		scheduling:   true,
+	}
+	s.addQueue("batch")
+	s.queues["batch"].enabled = true
+	s.queues["batch"].started = true
+	return s
+}
+
+// Connect returns a Client for the fake server. server must be empty or
+// the name of the FakeServer.
+func (s *FakeServer) Connect(server string) (Client, error) {
+	if server != "" && server != s.name {
+		return nil, &PBSError{Op: "pbs_connect", ID: server, Errno: PBSE_NOSERVER, Msg: errorText[PBSE_NOSERVER]}
+	}
+
+	owner := "nobody"
+	if u, err := user.Current(); err == nil {
+		owner = u.Username
+	}
+	return &fakeClient{server: s, owner: owner + "@" + s.name}, nil
+}
+
+// Name returns the name of the server, which is used as the suffix of job
+// identifiers
+func (s *FakeServer) Name() string {
+	return s.name
+}
+
+// DefaultServer returns the server's name, so that a Conn made with
+// ConnectWith knows it
+func (s *FakeServer) DefaultServer() string {
+	return s.name
+}
+
+// Now returns the current time on the server's clock
+func (s *FakeServer) Now() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.now
+}
+
+// Advance moves the server's clock forward by d, starting and completing
+// jobs as it goes. Advance(0) runs the scheduler without moving the clock.
+func (s *FakeServer) Advance(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	end := s.now.Add(d)
+	for {
+		s.schedule()
+
+		// Complete the jobs in the order in which they finish, so that
+		// their slots are reused by jobs started in the interval
+		var next *fakeJob
+		for _, id := range s.jobOrder {
+			j := s.jobs[id]
+			if j.state == StateRunning && !j.finish.After(end) && (next == nil || j.finish.Before(next.finish)) {
+				next = j
+			}
+		}
+		if next == nil {
+			break
+		}
+		s.now = next.finish
+		s.complete(next, 0)
+	}
+	s.now = end
+}
+
+// SetRuntime sets how long jobs started from now on will run for
+func (s *FakeServer) SetRuntime(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.runtime = d
+}
+
+// AddNode adds an execution node with np slots. Once a node has been added
+// jobs are only started when there is a free slot for them.
+func (s *FakeServer) AddNode(name string, np int, properties ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.addNode(name, np, properties)
+}
+
+// Script returns the contents of the script of the job id
+func (s *FakeServer) Script(id string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j, err := s.job("pbs_script", id)
+	if err != nil {
+		return nil, err
+	}
+	return j.contents, nil
+}
+
+// Finish completes the running job id with the given exit status
+func (s *FakeServer) Finish(id string, exitStatus int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j, err := s.job("pbs_finish", id)
+	if err != nil {
+		return err
+	}
+	if j.state != StateRunning {
+		return s.error("pbs_finish", id, PBSE_BADSTATE)
+	}
+	s.complete(j, exitStatus)
+	return nil
+}
+
+func (s *FakeServer) error(op string, id string, errno int) error {
+	msg := errorText[errno]
+	return &PBSError{
+		Op:        op,
+		ID:        id,
+		Errno:     errno,
+		Msg:       msg,
+		ServerMsg: strings.TrimSpace(msg + " " + id),
+	}
+}
+
+func (s *FakeServer) addQueue(name string) {
+	s.queues[name] = &fakeQueue{name: name}
+	s.queueOrder = append(s.queueOrder, name)
+}
+
+func (s *FakeServer) addNode(name string, np int, properties []string) {
+	s.nodes[name] = &fakeNode{
+		name:       name,
+		np:         np,
+		properties: properties,
+		slots:      make([]string, np),
+	}
+	s.nodeOrder = append(s.nodeOrder, name)
+}
+
+// job finds a job by its full or short identifier, "12" matches
+// "12.server".
+func (s *FakeServer) job(op string, id string) (*fakeJob, error) {
+	seq := id
+	if i := strings.Index(id, "."); i >= 0 {
+		seq = id[:i]
+	}
+	j, ok := s.jobs[seq]
+	if !ok {
+		return nil, s.error(op, id, PBSE_UNKJOBID)
+	}
+	return j, nil
+}
+
+// schedule starts every eligible queued job for which there is a free slot
+func (s *FakeServer) schedule() {
+	if !s.scheduling {
+		return
+	}
+	for _, id := range s.jobOrder {
+		j := s.jobs[id]
+		if j.state != StateQueued || j.holds != "" || j.subjobs != nil || !s.queues[j.queue].started {
+			continue
+		}
+		if p := j.parent; p != nil && p.limit > 0 && countState(p.subjobs, StateRunning) >= p.limit {
+			continue
+		}
+
+		host := s.name
+		if len(s.nodeOrder) > 0 {
+			host = s.allocate(j)
+			if host == "" {
+				continue
+			}
+		}
+
+		j.state = StateRunning
+		j.execHost = host
+		j.start = s.now
+		j.mtime = s.now
+		j.finish = s.now.Add(s.runtime)
+	}
+}
+
+// allocate reserves a slot for j, returning the exec_host entry or an
+// empty string if all of the slots are in use
+func (s *FakeServer) allocate(j *fakeJob) string {
+	for _, name := range s.nodeOrder {
+		n := s.nodes[name]
+		if n.offline {
+			continue
+		}
+		for i := range n.slots {
+			if n.slots[i] == "" {
+				n.slots[i] = j.id
+				return fmt.Sprintf("%s/%d", n.name, i)
+			}
+		}
+	}
+	return ""
+}
+
+func (s *FakeServer) release(j *fakeJob) {
+	for _, n := range s.nodes {
+		for i := range n.slots {
+			if n.slots[i] == j.id {
+				n.slots[i] = ""
+			}
+		}
+	}
+}
+
+func (s *FakeServer) complete(j *fakeJob, exitStatus int) {
+	s.release(j)
+	j.state = StateComplete
+	j.exitStatus = exitStatus
+	j.finish = s.now
+	j.mtime = s.now
+}
+
+func (s *FakeServer) remove(j *fakeJob) {
+	s.release(j)
+	delete(s.jobs, j.key)
+	for i, key := range s.jobOrder {
+		if key == j.key {
+			s.jobOrder = append(s.jobOrder[:i], s.jobOrder[i+1:]...)
+			break
+		}
+	}
+	if p := j.parent; p != nil {
+		for i, sub := range p.subjobs {
+			if sub == j {
+				p.subjobs = append(p.subjobs[:i], p.subjobs[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// targets returns the jobs which an operation on j applies to: j itself,
+// or the subjobs of the array j, either all of them or those in the
+// extend's array_range
+func (s *FakeServer) targets(op string, id string, j *fakeJob, extend string) ([]*fakeJob, error) {
+	if j.subjobs == nil {
+		return []*fakeJob{j}, nil
+	}
+	if !strings.HasPrefix(extend, arrayRangeExtend) {
+		return append([]*fakeJob(nil), j.subjobs...), nil
+	}
+
+	// Like pbs_server, the fake doesn't take ranges with steps
+	r, err := ParseArrayRange(strings.TrimPrefix(extend, arrayRangeExtend))
+	if err != nil || r.hasSteps() {
+		return nil, s.error(op, extend, PBSE_IVALREQ)
+	}
+	var jobs []*fakeJob
+	for _, sub := range j.subjobs {
+		if r.Contains(sub.index) {
+			jobs = append(jobs, sub)
+		}
+	}
+	if jobs == nil {
+		return nil, s.error(op, id, PBSE_UNKJOBID)
+	}
+	return jobs, nil
+}
+
+// fakeState returns the state reported for j: held jobs are queued with
+// holds, and arrays take the state of their most active subjob
+func fakeState(j *fakeJob) JobState {
+	if j.subjobs == nil {
+		if j.state == StateQueued && j.holds != "" {
+			return StateHeld
+		}
+		return j.state
+	}
+
+	state := StateComplete
+	for _, sub := range j.subjobs {
+		switch fakeState(sub) {
+		case StateRunning:
+			return StateRunning
+		case StateQueued:
+			state = StateQueued
+		case StateHeld:
+			if state == StateComplete {
+				state = StateHeld
+			}
+		}
+	}
+	return state
+}
+
+func countState(jobs []*fakeJob, state JobState) int {
+	n := 0
+	for _, j := range jobs {
+		if fakeState(j) == state {
+			n++
+		}
+	}
+	return n
+}
+
+func epoch(t time.Time) string {
+	return strconv.FormatInt(t.Unix(), 10)
+}
+
+// substates reported for each job state
+var fakeSubstates = map[JobState]JobSubstate{
+	StateQueued:   SubstateQueued,
+	StateHeld:     SubstateHeld,
+	StateRunning:  SubstateRunning,
+	StateComplete: SubstateComplete,
+}
+
+func (s *FakeServer) jobStatus(j *fakeJob) BatchStatus {
+	state := fakeState(j)
+	holds := j.holds
+	if holds == "" {
+		holds = "n"
+	}
+
+	attribs := []Attrib{
+		{Name: ATTR_N, Value: j.name},
+		{Name: ATTR_owner, Value: j.owner},
+		{Name: ATTR_state, Value: state.Letter()},
+		{Name: ATTR_queue, Value: j.queue},
+		{Name: ATTR_server, Value: s.name},
+		{Name: ATTR_ctime, Value: epoch(j.ctime)},
+		{Name: ATTR_h, Value: holds},
+		{Name: ATTR_mtime, Value: epoch(j.mtime)},
+		{Name: ATTR_qtime, Value: epoch(j.qtime)},
+		{Name: ATTR_etime, Value: epoch(j.qtime)},
+		{Name: ATTR_substate, Value: strconv.Itoa(int(fakeSubstates[state]))},
+		{Name: ATTR_euser, Value: strings.SplitN(j.owner, "@", 2)[0]},
+	}
+	for _, a := range j.attribs {
+		if attribValue(attribs, a.Name, a.Resource) == nil {
+			attribs = append(attribs, Attrib{Name: a.Name, Resource: a.Resource, Value: a.Value})
+		}
+	}
+
+	if j.state == StateRunning || j.state == StateComplete {
+		end := s.now
+		if j.state == StateComplete {
+			end = j.finish
+		}
+		attribs = append(attribs,
+			Attrib{Name: ATTR_exechost, Value: j.execHost},
+			Attrib{Name: ATTR_start_time, Value: epoch(j.start)},
+			Attrib{Name: ATTR_start_count, Value: "1"},
+			Attrib{Name: ATTR_used, Resource: "cput", Value: "00:00:00"},
+			Attrib{Name: ATTR_used, Resource: "walltime", Value: hms(end.Sub(j.start))},
+		)
+	}
+	if j.state == StateComplete {
+		attribs = append(attribs,
+			Attrib{Name: ATTR_exitstat, Value: strconv.Itoa(j.exitStatus)},
+			Attrib{Name: ATTR_comp_time, Value: epoch(j.finish)},
+		)
+	}
+
+	return BatchStatus{Name: j.id, Attributes: attribs}
+}
+
+func stateCount(jobs []*fakeJob) string {
+	counts := map[JobState]int{}
+	for _, j := range jobs {
+		counts[fakeState(j)]++
+	}
+	return fmt.Sprintf("Transit:%d Queued:%d Held:%d Waiting:%d Running:%d Exiting:%d Complete:%d ",
+		counts[StateTransit], counts[StateQueued], counts[StateHeld], counts[StateWaiting],
+		counts[StateRunning], counts[StateExiting], counts[StateComplete])
+}
+
+// queueJobs returns the jobs in queue, or in all queues if queue is empty,
+// with job arrays as their subjobs
+func (s *FakeServer) queueJobs(queue string) []*fakeJob {
+	return s.listJobs(queue, true)
+}
+
+// listJobs returns the jobs in queue, with job arrays as either their
+// subjobs or themselves
+func (s *FakeServer) listJobs(queue string, subjobs bool) []*fakeJob {
+	var jobs []*fakeJob
+	for _, id := range s.jobOrder {
+		j := s.jobs[id]
+		if queue != "" && j.queue != queue {
+			continue
+		}
+		if subjobs && j.subjobs == nil || !subjobs && j.parent == nil {
+			jobs = append(jobs, j)
+		}
+	}
+	return jobs
+}
+
+func boolValue(b bool) string {
+	if b {
+		return "True"
+	}
+	return "False"
+}
+
+func (s *FakeServer) queueStatus(q *fakeQueue) BatchStatus {
+	jobs := s.queueJobs(q.name)
+	attribs := []Attrib{
+		{Name: "queue_type", Value: "Execution"},
+		{Name: ATTR_total, Value: strconv.Itoa(len(jobs))},
+		{Name: "state_count", Value: stateCount(jobs)},
+	}
+	attribs = append(attribs, q.attribs...)
+	attribs = append(attribs,
+		Attrib{Name: "enabled", Value: boolValue(q.enabled)},
+		Attrib{Name: "started", Value: boolValue(q.started)},
+	)
+	return BatchStatus{Name: q.name, Attributes: attribs}
+}
+
+func (s *FakeServer) serverStatus() BatchStatus {
+	jobs := s.queueJobs("")
+	attribs := []Attrib{
+		{Name: "server_state", Value: "Active"},
+		{Name: "scheduling", Value: boolValue(s.scheduling)},
+		{Name: ATTR_total, Value: strconv.Itoa(len(jobs))},
+		{Name: "state_count", Value: stateCount(jobs)},
+		{Name: "default_queue", Value: s.defaultQueue},
+	}
+	attribs = append(attribs, s.attribs...)
+	return BatchStatus{Name: s.name, Attributes: attribs}
+}
+
+func (s *FakeServer) nodeStatus(n *fakeNode) BatchStatus {
+	var jobs []string
+	for i, id := range n.slots {
+		if id != "" {
+			jobs = append(jobs, fmt.Sprintf("%d/%s", i, id))
+		}
+	}
+
+	state := "free"
+	switch {
+	case n.offline:
+		state = "offline"
+	case len(jobs) == len(n.slots):
+		state = "job-exclusive"
+	}
+
+	attribs := []Attrib{
+		{Name: "state", Value: state},
+		{Name: "np", Value: strconv.Itoa(n.np)},
+		{Name: "properties", Value: strings.Join(n.properties, ",")},
+		{Name: "ntype", Value: "cluster"},
+	}
+	if len(jobs) > 0 {
+		attribs = append(attribs, Attrib{Name: "jobs", Value: strings.Join(jobs, ",")})
+	}
+	attribs = append(attribs, n.attribs...)
+	attribs = append(attribs, Attrib{Name: "gpus", Value: "0"})
+	return BatchStatus{Name: n.name, Attributes: attribs}
+}
+
+// attribValue returns the attribute called name with resource in attribs
+func attribValue(attribs []Attrib, name string, resource string) *Attrib {
+	for i := range attribs {
+		if attribs[i].Name == name && attribs[i].Resource == resource {
+			return &attribs[i]
+		}
+	}
+	return nil
+}
+
+// setAttribs applies attribs to list as pbs_manager and pbs_alterjob do
+func setAttribs(list []Attrib, attribs []Attrib, unset bool) []Attrib {
+	for _, a := range attribs {
+		var kept []Attrib
+		for _, l := range list {
+			if l.Name != a.Name || (a.Resource != "" && l.Resource != a.Resource) {
+				kept = append(kept, l)
+			}
+		}
+		list = kept
+		if !unset && a.Op != UNSET {
+			list = append(list, Attrib{Name: a.Name, Resource: a.Resource, Value: a.Value})
+		}
+	}
+	return list
+}
+
+// filterStatus returns status with only the attributes named in attribs,
+// or all of them if attribs is empty
+func filterStatus(status BatchStatus, attribs []Attrib) BatchStatus {
+	if len(attribs) == 0 {
+		return status
+	}
+	var kept []Attrib
+	for _, a := range status.Attributes {
+		for _, want := range attribs {
+			if a.Name == want.Name {
+				kept = append(kept, a)
+				break
+			}
+		}
+	}
+	status.Attributes = kept
+	return status
+}
+
+// selected reports whether status matches every selection criteria in
+// attribs, as pbs_selectjob does
+func selected(status BatchStatus, attribs []Attrib) bool {
+	for _, want := range attribs {
+		name := want.Name
+		if name == ATTR_u {
+			name = ATTR_euser
+		}
+
+		var have *Attrib
+		for i := range status.Attributes {
+			if status.Attributes[i].Name == name && status.Attributes[i].Resource == want.Resource {
+				have = &status.Attributes[i]
+			}
+		}
+		if have == nil {
+			return false
+		}
+
+		cmp := compareValues(have.Value, want.Value)
+		switch want.Op {
+		case NE:
+			if cmp == 0 {
+				return false
+			}
+		case GE:
+			if cmp < 0 {
+				return false
+			}
+		case GT:
+			if cmp <= 0 {
+				return false
+			}
+		case LE:
+			if cmp > 0 {
+				return false
+			}
+		case LT:
+			if cmp >= 0 {
+				return false
+			}
+		default:
+			if name == ATTR_state {
+				// Several states can be selected at once, e.g. "QR"
+				if !strings.Contains(want.Value, have.Value) {
+					return false
+				}
+			} else if cmp != 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// compareValues compares a and b numerically if they are both integers
+func compareValues(a string, b string) int {
+	x, errx := strconv.Atoi(a)
+	y, erry := strconv.Atoi(b)
+	if errx == nil && erry == nil {
+		return x - y
+	}
+	return strings.Compare(a, b)
+}
+
+// fakeClient is a Client connected to a FakeServer
+type fakeClient struct {
+	server *FakeServer
+	owner  string
+	// closed is guarded by the server's mutex
+	closed bool
+}
+
+func (c *fakeClient) lock(op string) (*FakeServer, error) {
+	c.server.mu.Lock()
+	if c.closed {
+		c.server.mu.Unlock()
+		return nil, &PBSError{Op: op, Errno: PBSE_PROTOCOL, Msg: errorText[PBSE_PROTOCOL]}
+	}
+	return c.server, nil
+}
+
+func (c *fakeClient) Submit(attribs []Attrib, script string, destination string, extend string) (string, error) {
+	// The script is kept if it can be read, but unlike pbs_submit a missing
+	// script isn't an error
+	contents, _ := os.ReadFile(script)
+	return c.submit(attribs, script, contents, destination)
+}
+
+// SubmitScript submits a job with the script's contents, as the DIS
+// backend's Clients can
+func (c *fakeClient) SubmitScript(attribs []Attrib, script []byte, destination string, extend string) (string, error) {
+	return c.submit(attribs, "STDIN", append([]byte(nil), script...), destination)
+}
+
+func (c *fakeClient) submit(attribs []Attrib, script string, contents []byte, destination string) (string, error) {
+	s, err := c.lock("pbs_submit")
+	if err != nil {
+		return "", err
+	}
+	defer s.mu.Unlock()
+
+	queue := strings.SplitN(destination, "@", 2)[0]
+	if queue == "" {
+		queue = s.defaultQueue
+	}
+	q, ok := s.queues[queue]
+	if !ok {
+		return "", s.error("pbs_submit", destination, PBSE_UNKQUE)
+	}
+	if !q.enabled {
+		return "", s.error("pbs_submit", destination, PBSE_QUNOENB)
+	}
+
+	var array *ArrayRange
+	for _, a := range attribs {
+		if a.Name == ATTR_t {
+			r, err := ParseArrayRange(a.Value)
+			if err != nil || r.hasSteps() {
+				return "", s.error("pbs_submit", a.Value, PBSE_BADATVAL)
+			}
+			array = &r
+		}
+	}
+
+	s.seq++
+	j := &fakeJob{
+		id:       fmt.Sprintf("%d.%s", s.seq, s.name),
+		key:      strconv.Itoa(s.seq),
+		seq:      s.seq,
+		name:     filepath.Base(script),
+		owner:    c.owner,
+		queue:    queue,
+		script:   script,
+		contents: contents,
+		state:    StateQueued,
+		ctime:    s.now,
+		mtime:    s.now,
+		qtime:    s.now,
+	}
+	for _, a := range attribs {
+		switch a.Name {
+		case ATTR_N:
+			j.name = a.Value
+		case ATTR_h:
+			j.holds = strings.Trim(a.Value, "n")
+		default:
+			j.attribs = setAttribs(j.attribs, []Attrib{a}, false)
+		}
+	}
+
+	s.addJob(j)
+	if array != nil {
+		s.addSubjobs(j, *array)
+	}
+	return j.id, nil
+}
+
+func (s *FakeServer) addJob(j *fakeJob) {
+	s.jobs[j.key] = j
+	s.jobOrder = append(s.jobOrder, j.key)
+}
+
+// addSubjobs makes j a job array with a subjob for each index of r, called
+// name-index as pbs_server does
+func (s *FakeServer) addSubjobs(j *fakeJob, r ArrayRange) {
+	delete(s.jobs, j.key)
+	j.key = fmt.Sprintf("%d[]", j.seq)
+	j.id = j.key + "." + s.name
+	j.limit = r.Limit
+	s.jobs[j.key] = j
+	s.jobOrder[len(s.jobOrder)-1] = j.key
+
+	for _, i := range r.Indices() {
+		sub := *j
+		sub.key = fmt.Sprintf("%d[%d]", j.seq, i)
+		sub.id = sub.key + "." + s.name
+		sub.name = fmt.Sprintf("%s-%d", j.name, i)
+		sub.parent, sub.index, sub.limit, sub.subjobs = j, i, 0, nil
+		sub.attribs = nil
+		for _, a := range j.attribs {
+			if a.Name != ATTR_t {
+				sub.attribs = append(sub.attribs, a)
+			}
+		}
+		sub.attribs = append(sub.attribs, Attrib{Name: ATTR_array_id, Value: strconv.Itoa(i)})
+		j.subjobs = append(j.subjobs, &sub)
+		s.addJob(&sub)
+	}
+}
+
+func (c *fakeClient) StatJob(id string, attribs []Attrib, extend string) ([]BatchStatus, error) {
+	s, err := c.lock("pbs_statjob")
+	if err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	subjobs := strings.Contains(extend, ExtendSubjobs)
+	var jobs []*fakeJob
+	if q, ok := s.queues[id]; ok {
+		jobs = s.listJobs(q.name, subjobs)
+	} else if id == "" {
+		jobs = s.listJobs("", subjobs)
+	} else {
+		j, err := s.job("pbs_statjob", id)
+		if err != nil {
+			return nil, err
+		}
+		if subjobs && j.subjobs != nil {
+			jobs = append(jobs, j.subjobs...)
+		} else {
+			jobs = append(jobs, j)
+		}
+	}
+
+	var batch []BatchStatus
+	for _, j := range jobs {
+		batch = append(batch, filterStatus(s.jobStatus(j), attribs))
+	}
+	return batch, nil
+}
+
+func (c *fakeClient) StatNode(id string, attribs []Attrib, extend string) ([]BatchStatus, error) {
+	s, err := c.lock("pbs_statnode")
+	if err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	if len(s.nodeOrder) == 0 {
+		return nil, s.error("pbs_statnode", id, PBSE_NONODES)
+	}
+
+	var batch []BatchStatus
+	for _, name := range s.nodeOrder {
+		if id == "" || id == name {
+			batch = append(batch, filterStatus(s.nodeStatus(s.nodes[name]), attribs))
+		}
+	}
+	if batch == nil {
+		return nil, s.error("pbs_statnode", id, PBSE_UNKNODE)
+	}
+	return batch, nil
+}
+
+func (c *fakeClient) StatQue(id string, attribs []Attrib, extend string) ([]BatchStatus, error) {
+	s, err := c.lock("pbs_statque")
+	if err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	var batch []BatchStatus
+	for _, name := range s.queueOrder {
+		if id == "" || id == name {
+			batch = append(batch, filterStatus(s.queueStatus(s.queues[name]), attribs))
+		}
+	}
+	if batch == nil {
+		return nil, s.error("pbs_statque", id, PBSE_UNKQUE)
+	}
+	return batch, nil
+}
+
+func (c *fakeClient) StatServer(attribs []Attrib, extend string) ([]BatchStatus, error) {
+	s, err := c.lock("pbs_statserver")
+	if err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	return []BatchStatus{filterStatus(s.serverStatus(), attribs)}, nil
+}
+
+func (c *fakeClient) selectJobs(attribs []Attrib) []*fakeJob {
+	var jobs []*fakeJob
+	for _, j := range c.server.queueJobs("") {
+		if selected(c.server.jobStatus(j), attribs) {
+			jobs = append(jobs, j)
+		}
+	}
+	return jobs
+}
+
+func (c *fakeClient) SelectJob(attribs []Attrib, extend string) ([]string, error) {
+	s, err := c.lock("pbs_selectjob")
+	if err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	var ids []string
+	for _, j := range c.selectJobs(attribs) {
+		ids = append(ids, j.id)
+	}
+	return ids, nil
+}
+
+func (c *fakeClient) SelStat(attribs []Attrib, extend string) ([]BatchStatus, error) {
+	s, err := c.lock("pbs_selstat")
+	if err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	var batch []BatchStatus
+	for _, j := range c.selectJobs(attribs) {
+		batch = append(batch, s.jobStatus(j))
+	}
+	return batch, nil
+}
+
+func (c *fakeClient) HoldJob(id string, holdType Hold, extend string) error {
+	s, err := c.lock("pbs_holdjob")
+	if err != nil {
+		return err
+	}
+	defer s.mu.Unlock()
+
+	target, err := s.job("pbs_holdjob", id)
+	if err != nil {
+		return err
+	}
+	jobs, err := s.targets("pbs_holdjob", id, target, extend)
+	if err != nil {
+		return err
+	}
+	for _, j := range jobs {
+		if j.state != StateQueued {
+			// The subjobs of arrays which have started are left alone
+			if target.subjobs != nil {
+				continue
+			}
+			return s.error("pbs_holdjob", id, PBSE_BADSTATE)
+		}
+		for _, h := range string(holdType) {
+			if !strings.ContainsRune(j.holds, h) {
+				j.holds += string(h)
+			}
+		}
+		j.mtime = s.now
+	}
+	return nil
+}
+
+func (c *fakeClient) RlsJob(id string, holdType Hold, extend string) error {
+	s, err := c.lock("pbs_rlsjob")
+	if err != nil {
+		return err
+	}
+	defer s.mu.Unlock()
+
+	target, err := s.job("pbs_rlsjob", id)
+	if err != nil {
+		return err
+	}
+	jobs, err := s.targets("pbs_rlsjob", id, target, extend)
+	if err != nil {
+		return err
+	}
+	for _, j := range jobs {
+		if j.state != StateQueued {
+			if target.subjobs != nil {
+				continue
+			}
+			return s.error("pbs_rlsjob", id, PBSE_BADSTATE)
+		}
+		for _, h := range string(holdType) {
+			j.holds = strings.Replace(j.holds, string(h), "", -1)
+		}
+		j.mtime = s.now
+	}
+	return nil
+}
+
+func (c *fakeClient) DelJob(id string, extend string) error {
+	s, err := c.lock("pbs_deljob")
+	if err != nil {
+		return err
+	}
+	defer s.mu.Unlock()
+
+	target, err := s.job("pbs_deljob", id)
+	if err != nil {
+		return err
+	}
+	jobs, err := s.targets("pbs_deljob", id, target, extend)
+	if err != nil {
+		return err
+	}
+	for _, j := range jobs {
+		switch j.state {
+		case StateQueued:
+			s.remove(j)
+		case StateRunning:
+			s.complete(j, 256+fakeSignals["TERM"])
+		default:
+			if target.subjobs == nil {
+				return s.error("pbs_deljob", id, PBSE_BADSTATE)
+			}
+		}
+	}
+	if target.subjobs != nil && len(target.subjobs) == 0 {
+		s.remove(target)
+	}
+	return nil
+}
+
+func (c *fakeClient) AlterJob(id string, attribs []Attrib, extend string) error {
+	s, err := c.lock("pbs_alterjob")
+	if err != nil {
+		return err
+	}
+	defer s.mu.Unlock()
+
+	target, err := s.job("pbs_alterjob", id)
+	if err != nil {
+		return err
+	}
+	jobs, err := s.targets("pbs_alterjob", id, target, extend)
+	if err != nil {
+		return err
+	}
+	for _, j := range jobs {
+		if j.state == StateComplete {
+			if target.subjobs != nil {
+				continue
+			}
+			return s.error("pbs_alterjob", id, PBSE_BADSTATE)
+		}
+		for _, a := range attribs {
+			switch a.Name {
+			case ATTR_N:
+				j.name = a.Value
+			case ATTR_h:
+				j.holds = strings.Trim(a.Value, "n")
+			case ATTR_state, ATTR_owner, ATTR_queue, ATTR_server, ATTR_ctime, ATTR_mtime, ATTR_qtime, ATTR_exechost, ATTR_t, ATTR_array_id:
+				return s.error("pbs_alterjob", id, PBSE_ATTRRO)
+			default:
+				if j.state == StateRunning && a.Name == ATTR_l {
+					return s.error("pbs_alterjob", id, PBSE_MODATRRUN)
+				}
+				j.attribs = setAttribs(j.attribs, []Attrib{a}, false)
+			}
+		}
+		j.mtime = s.now
+	}
+	return nil
+}
+
+func (c *fakeClient) MoveJob(id string, destination string, extend string) error {
+	s, err := c.lock("pbs_movejob")
+	if err != nil {
+		return err
+	}
+	defer s.mu.Unlock()
+
+	j, err := s.job("pbs_movejob", id)
+	if err != nil {
+		return err
+	}
+	queue := strings.SplitN(destination, "@", 2)[0]
+	q, ok := s.queues[queue]
+	if !ok {
+		return s.error("pbs_movejob", destination, PBSE_UNKQUE)
+	}
+	if !q.enabled {
+		return s.error("pbs_movejob", destination, PBSE_QUNOENB)
+	}
+	jobs := append([]*fakeJob{j}, j.subjobs...)
+	for _, j := range jobs {
+		if j.state != StateQueued {
+			return s.error("pbs_movejob", id, PBSE_BADSTATE)
+		}
+	}
+	for _, j := range jobs {
+		j.queue = queue
+		j.qtime = s.now
+		j.mtime = s.now
+	}
+	return nil
+}
+
+func (c *fakeClient) SigJob(id string, signal string, extend string) error {
+	s, err := c.lock("pbs_sigjob")
+	if err != nil {
+		return err
+	}
+	defer s.mu.Unlock()
+
+	target, err := s.job("pbs_sigjob", id)
+	if err != nil {
+		return err
+	}
+	jobs, err := s.targets("pbs_sigjob", id, target, extend)
+	if err != nil {
+		return err
+	}
+	if target.subjobs == nil && target.state != StateRunning {
+		return s.error("pbs_sigjob", id, PBSE_BADSTATE)
+	}
+
+	num, ok := fakeSignals[strings.TrimPrefix(strings.ToUpper(signal), "SIG")]
+	if !ok {
+		num, err = strconv.Atoi(signal)
+		if err != nil || num < 1 || num > 64 {
+			return s.error("pbs_sigjob", signal, PBSE_UNKSIG)
+		}
+	}
+	for _, j := range jobs {
+		if j.state == StateRunning && (num == 9 || num == 15) {
+			// Killed jobs exit with 256 + the signal number
+			s.complete(j, 256+num)
+		}
+	}
+	return nil
+}
+
+var fakeSignals = map[string]int{
+	"HUP":  1,
+	"INT":  2,
+	"QUIT": 3,
+	"KILL": 9,
+	"USR1": 10,
+	"USR2": 12,
+	"TERM": 15,
+	"CONT": 18,
+	"STOP": 19,
+}
+
+func (c *fakeClient) Manager(command Command, objType ObjectType, name string, attribs []Attrib, extend string) error {
+	s, err := c.lock("pbs_manager")
+	if err != nil {
+		return err
+	}
+	defer s.mu.Unlock()
+
+	switch objType {
+	case MGR_OBJ_SERVER:
+		return s.manageServer(command, attribs)
+	case MGR_OBJ_QUEUE:
+		return s.manageQueue(command, name, attribs)
+	case MGR_OBJ_NODE:
+		return s.manageNode(command, name, attribs)
+	}
+	return s.error("pbs_manager", name, PBSE_NOSUP)
+}
+
+func (s *FakeServer) manageServer(command Command, attribs []Attrib) error {
+	if command != MGR_CMD_SET && command != MGR_CMD_UNSET {
+		return s.error("pbs_manager", s.name, PBSE_IVALREQ)
+	}
+	for _, a := range attribs {
+		switch a.Name {
+		case "scheduling":
+			s.scheduling = command == MGR_CMD_SET && parseBool(a.Value)
+		case "default_queue":
+			if command == MGR_CMD_SET {
+				if _, ok := s.queues[a.Value]; !ok {
+					return s.error("pbs_manager", a.Value, PBSE_UNKQUE)
+				}
+				s.defaultQueue = a.Value
+			} else {
+				s.defaultQueue = ""
+			}
+		default:
+			s.attribs = setAttribs(s.attribs, []Attrib{a}, command == MGR_CMD_UNSET)
+		}
+	}
+	return nil
+}
+
+func (s *FakeServer) manageQueue(command Command, name string, attribs []Attrib) error {
+	q, ok := s.queues[name]
+	switch command {
+	case MGR_CMD_CREATE:
+		if ok {
+			return s.error("pbs_manager", name, PBSE_QUEEXIST)
+		}
+		s.addQueue(name)
+		q = s.queues[name]
+	case MGR_CMD_DELETE:
+		if !ok {
+			return s.error("pbs_manager", name, PBSE_UNKQUE)
+		}
+		if len(s.queueJobs(name)) > 0 {
+			return s.error("pbs_manager", name, PBSE_QUEBUSY)
+		}
+		delete(s.queues, name)
+		for i, n := range s.queueOrder {
+			if n == name {
+				s.queueOrder = append(s.queueOrder[:i], s.queueOrder[i+1:]...)
+				break
+			}
+		}
+		return nil
+	case MGR_CMD_SET, MGR_CMD_UNSET:
+		if !ok {
+			return s.error("pbs_manager", name, PBSE_UNKQUE)
+		}
+	default:
+		return s.error("pbs_manager", name, PBSE_IVALREQ)
+	}
+
+	for _, a := range attribs {
+		switch a.Name {
+		case "enabled":
+			q.enabled = command != MGR_CMD_UNSET && parseBool(a.Value)
+		case "started":
+			q.started = command != MGR_CMD_UNSET && parseBool(a.Value)
+		default:
+			q.attribs = setAttribs(q.attribs, []Attrib{a}, command == MGR_CMD_UNSET)
+		}
+	}
+	return nil
+}
+
+func (s *FakeServer) manageNode(command Command, name string, attribs []Attrib) error {
+	n, ok := s.nodes[name]
+	switch command {
+	case MGR_CMD_CREATE:
+		if ok {
+			return s.error("pbs_manager", name, PBSE_NODEEXIST)
+		}
+		s.addNode(name, 1, nil)
+		n = s.nodes[name]
+	case MGR_CMD_DELETE:
+		if !ok {
+			return s.error("pbs_manager", name, PBSE_UNKNODE)
+		}
+		delete(s.nodes, name)
+		for i, o := range s.nodeOrder {
+			if o == name {
+				s.nodeOrder = append(s.nodeOrder[:i], s.nodeOrder[i+1:]...)
+				break
+			}
+		}
+		return nil
+	case MGR_CMD_SET, MGR_CMD_UNSET:
+		if !ok {
+			return s.error("pbs_manager", name, PBSE_UNKNODE)
+		}
+	default:
+		return s.error("pbs_manager", name, PBSE_IVALREQ)
+	}
+
+	for _, a := range attribs {
+		switch a.Name {
+		case "np":
+			np, err := strconv.Atoi(a.Value)
+			if err != nil || np < 1 {
+				return s.error("pbs_manager", name, PBSE_BADNDATVAL)
+			}
+			// The slots which would be cut off mustn't have jobs
+			for i := np; i < len(n.slots); i++ {
+				if n.slots[i] != "" {
+					return s.error("pbs_manager", name, PBSE_BADNDATVAL)
+				}
+			}
+			slots := make([]string, np)
+			copy(slots, n.slots)
+			n.np, n.slots = np, slots
+		case "properties":
+			n.properties = strings.Split(a.Value, ",")
+		case "state":
+			switch a.Op {
+			case DECR:
+				n.offline = n.offline && !strings.Contains(a.Value, "offline")
+			default:
+				n.offline = strings.Contains(a.Value, "offline")
+			}
+		default:
+			n.attribs = setAttribs(n.attribs, []Attrib{a}, command == MGR_CMD_UNSET)
+		}
+	}
+	return nil
+}
+
+func parseBool(s string) bool {
+	switch strings.ToLower(s) {
+	case "true", "t", "y", "yes", "1":
+		return true
+	}
+	return false
+}
+
+func (c *fakeClient) Disconnect() error {
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+	if c.closed {
+		return &PBSError{Op: "pbs_disconnect", Errno: PBSE_PROTOCOL, Msg: errorText[PBSE_PROTOCOL]}
+	}
+	c.closed = true
+	return nil
+}
@@ -0,0 +1,280 @@
+package pbs
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func connectFake(t *testing.T, s *FakeServer) Client {
+	client, err := s.Connect("")
+	if err != nil {
+		t.Fatalf("Connect to fake server failed: %s\n", err)
+	}
+	return client
+}
+
+func jobAttribute(t *testing.T, client Client, id string, name string) string {
+	status, err := client.StatJob(id, nil, "")
+	if err != nil {
+		t.Fatalf("Couldn't get job attributes for %s: %s\n", id, err)
+	}
+	if len(status) != 1 {
+		t.Fatalf("Expected one status for %s, got %d\n", id, len(status))
+	}
+	for _, attr := range status[0].Attributes {
+		if attr.Name == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+func TestFakeLifecycle(t *testing.T) {
+	s := NewFakeServer("fake")
+	s.SetRuntime(10 * time.Minute)
+	client := connectFake(t, s)
+
+	jobid, err := client.Submit([]Attrib{{Name: ATTR_N, Value: "lifecycle"}}, "test.sh", "", "")
+	if err != nil {
+		t.Fatalf("Job submission failed: %s\n", err)
+	}
+	if jobid != "1.fake" {
+		t.Errorf("Unexpected jobid %s\n", jobid)
+	}
+
+	if state := jobAttribute(t, client, jobid, ATTR_state); state != "Q" {
+		t.Errorf("Submitted job is in state %s\n", state)
+	}
+
+	steps := []struct {
+		advance time.Duration
+		state   string
+	}{
+		{0, "R"},
+		{5 * time.Minute, "R"},
+		{5 * time.Minute, "C"},
+	}
+	for _, step := range steps {
+		s.Advance(step.advance)
+		if state := jobAttribute(t, client, jobid, ATTR_state); state != step.state {
+			t.Errorf("After %s job is in state %s, expected %s\n", step.advance, state, step.state)
+		}
+	}
+
+	if name := jobAttribute(t, client, jobid, ATTR_N); name != "lifecycle" {
+		t.Errorf("Job_Name is %s\n", name)
+	}
+	if status := jobAttribute(t, client, jobid, ATTR_exitstat); status != "0" {
+		t.Errorf("exit_status is %s\n", status)
+	}
+}
+
+func TestFakeNodeSlots(t *testing.T) {
+	s := NewFakeServer("fake")
+	s.AddNode("node01", 1)
+	client := connectFake(t, s)
+
+	first, _ := client.Submit(nil, "test.sh", "", "")
+	second, _ := client.Submit(nil, "test.sh", "", "")
+
+	s.Advance(0)
+	if state := jobAttribute(t, client, second, ATTR_state); state != "Q" {
+		t.Errorf("Second job should wait for a slot, state is %s\n", state)
+	}
+	if host := jobAttribute(t, client, first, ATTR_exechost); host != "node01/0" {
+		t.Errorf("exec_host is %s\n", host)
+	}
+
+	// The second job starts when the first finishes and then runs for its
+	// full runtime
+	s.Advance(90 * time.Second)
+	if state := jobAttribute(t, client, first, ATTR_state); state != "C" {
+		t.Errorf("First job state is %s\n", state)
+	}
+	if state := jobAttribute(t, client, second, ATTR_state); state != "R" {
+		t.Errorf("Second job state is %s\n", state)
+	}
+}
+
+func TestFakeNodeBusySlots(t *testing.T) {
+	s := NewFakeServer("fake")
+	s.AddNode("node01", 2)
+	client := connectFake(t, s)
+
+	client.Submit(nil, "test.sh", "", "")
+	client.Submit(nil, "test.sh", "", "")
+	s.Advance(0)
+
+	// The second slot has a running job, so it can't be taken away
+	np := []Attrib{{Name: "np", Value: "1", Op: SET}}
+	var pe *PBSError
+	if err := client.Manager(MGR_CMD_SET, MGR_OBJ_NODE, "node01", np, ""); !errors.As(err, &pe) || pe.Errno != PBSE_BADNDATVAL {
+		t.Errorf("Lowering np of a busy node returned %v\n", err)
+	}
+	s.Advance(2 * time.Minute)
+	if err := client.Manager(MGR_CMD_SET, MGR_OBJ_NODE, "node01", np, ""); err != nil {
+		t.Errorf("Lowering np of an idle node failed: %s\n", err)
+	}
+}
+
+func TestFakeHoldRelease(t *testing.T) {
+	s := NewFakeServer("fake")
+	client := connectFake(t, s)
+
+	jobid, _ := client.Submit(nil, "test.sh", "", "")
+	if err := client.HoldJob(jobid, USER_HOLD, ""); err != nil {
+		t.Fatalf("Hold failed: %s\n", err)
+	}
+
+	s.Advance(time.Hour)
+	if state := jobAttribute(t, client, jobid, ATTR_state); state != "H" {
+		t.Errorf("Held job is in state %s\n", state)
+	}
+	if holds := jobAttribute(t, client, jobid, ATTR_h); holds != "u" {
+		t.Errorf("Hold_Types is %s\n", holds)
+	}
+
+	if err := client.RlsJob(jobid, USER_HOLD, ""); err != nil {
+		t.Fatalf("Release failed: %s\n", err)
+	}
+	s.Advance(0)
+	if state := jobAttribute(t, client, jobid, ATTR_state); state != "R" {
+		t.Errorf("Released job is in state %s\n", state)
+	}
+
+	err := client.HoldJob(jobid, USER_HOLD, "")
+	if !errors.Is(err, ErrBadState) {
+		t.Errorf("Holding a running job returned %v\n", err)
+	}
+}
+
+func TestFakeErrors(t *testing.T) {
+	s := NewFakeServer("fake")
+	client := connectFake(t, s)
+
+	if _, err := s.Connect("elsewhere"); !errors.Is(err, ErrNoServer) {
+		t.Errorf("Connect to another server returned %v\n", err)
+	}
+
+	_, err := client.StatJob("42.fake", nil, "")
+	if !errors.Is(err, ErrUnknownJob) {
+		t.Errorf("StatJob of unknown job returned %v\n", err)
+	}
+	var pe *PBSError
+	if !errors.As(err, &pe) || pe.Op != "pbs_statjob" || pe.ID != "42.fake" {
+		t.Errorf("Unexpected error %#v\n", err)
+	}
+
+	if err := client.DelJob("42", ""); !errors.Is(err, ErrUnknownJob) {
+		t.Errorf("DelJob of unknown job returned %v\n", err)
+	}
+	if _, err := client.Submit(nil, "test.sh", "nosuchqueue", ""); !errors.Is(err, ErrUnknownQueue) {
+		t.Errorf("Submit to unknown queue returned %v\n", err)
+	}
+
+	if err := client.Disconnect(); err != nil {
+		t.Fatalf("Disconnect failed: %s\n", err)
+	}
+	if _, err := client.StatServer(nil, ""); !errors.Is(err, ErrProtocol) {
+		t.Errorf("StatServer after Disconnect returned %v\n", err)
+	}
+}
+
+func TestFakeConcurrentDisconnect(t *testing.T) {
+	s := NewFakeServer("fake")
+	client := connectFake(t, s)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				client.Submit(nil, "test.sh", "", "")
+			}
+		}()
+	}
+	client.Disconnect()
+	wg.Wait()
+	if err := client.Disconnect(); !errors.Is(err, ErrProtocol) {
+		t.Errorf("Second Disconnect returned %v\n", err)
+	}
+}
+
+func TestFakeDelete(t *testing.T) {
+	s := NewFakeServer("fake")
+	client := connectFake(t, s)
+
+	queued, _ := client.Submit(nil, "test.sh", "", "")
+	if err := client.DelJob(queued, ""); err != nil {
+		t.Fatalf("Deleting queued job failed: %s\n", err)
+	}
+	if _, err := client.StatJob(queued, nil, ""); !errors.Is(err, ErrUnknownJob) {
+		t.Errorf("Deleted queued job still exists: %v\n", err)
+	}
+
+	running, _ := client.Submit(nil, "test.sh", "", "")
+	s.Advance(0)
+	if err := client.DelJob(running, ""); err != nil {
+		t.Fatalf("Deleting running job failed: %s\n", err)
+	}
+	if status := jobAttribute(t, client, running, ATTR_exitstat); status != "271" {
+		t.Errorf("Deleted running job has exit_status %s\n", status)
+	}
+}
+
+func TestFakeSelect(t *testing.T) {
+	s := NewFakeServer("fake")
+	client := connectFake(t, s)
+
+	if err := client.Manager(MGR_CMD_CREATE, MGR_OBJ_QUEUE, "long", []Attrib{
+		{Name: "enabled", Value: "True", Op: SET},
+	}, ""); err != nil {
+		t.Fatalf("Creating queue failed: %s\n", err)
+	}
+
+	client.Submit(nil, "test.sh", "", "")
+	long, _ := client.Submit(nil, "test.sh", "long", "")
+
+	jobs, err := client.SelectJob([]Attrib{{Name: ATTR_queue, Value: "long", Op: EQ}}, "")
+	if err != nil {
+		t.Fatalf("SelectJob failed: %s\n", err)
+	}
+	if len(jobs) != 1 || jobs[0] != long {
+		t.Errorf("Selected %v, expected [%s]\n", jobs, long)
+	}
+
+	// The long queue isn't started, so only the job in batch runs
+	s.Advance(0)
+	jobs, _ = client.SelectJob([]Attrib{{Name: ATTR_state, Value: "Q", Op: EQ}}, "")
+	if len(jobs) != 1 || jobs[0] != long {
+		t.Errorf("Selected %v, expected [%s]\n", jobs, long)
+	}
+
+	if err := client.Manager(MGR_CMD_CREATE, MGR_OBJ_QUEUE, "disabled", nil, ""); err != nil {
+		t.Fatalf("Creating queue failed: %s\n", err)
+	}
+	var pe *PBSError
+	if err := client.MoveJob(long, "disabled", ""); !errors.As(err, &pe) || pe.Errno != PBSE_QUNOENB {
+		t.Errorf("Moving a job to a disabled queue returned %v\n", err)
+	}
+
+	if err := client.Manager(MGR_CMD_DELETE, MGR_OBJ_QUEUE, "long", nil, ""); !errors.Is(err, ErrQueueBusy) {
+		t.Errorf("Deleting busy queue returned %v\n", err)
+	}
+	if err := client.Manager(MGR_CMD_CREATE, MGR_OBJ_QUEUE, "long", nil, ""); !errors.Is(err, ErrQueueExists) {
+		t.Errorf("Creating existing queue returned %v\n", err)
+	}
+
+	queues, err := client.StatQue("long", nil, "")
+	if err != nil {
+		t.Fatalf("StatQue failed: %s\n", err)
+	}
+	for _, attr := range queues[0].Attributes {
+		if attr.Name == "state_count" && attr.Value != "Transit:0 Queued:1 Held:0 Waiting:0 Running:0 Exiting:0 Complete:0 " {
+			t.Errorf("Unexpected state_count %q\n", attr.Value)
+		}
+	}
+}
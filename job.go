@@ -0,0 +1,149 @@
+package pbs
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Job is the status of a job, decoded from the BatchStatus returned by
+// Pbs_statjob or Pbs_selstat
+type Job struct {
+	ID       string
+	Name     string
+	Owner    string
+	State    JobState
+	Substate JobSubstate
+	Queue    string
+	Server   string
+	ExecHost string
+
+	Ctime     time.Time
+	Mtime     time.Time
+	Qtime     time.Time
+	Etime     time.Time
+	StartTime time.Time
+
+	// ExitStatus is only set once the job has completed
+	ExitStatus int
+
+	// Resources are the resources requested, from Resource_List
+	Resources map[string]string
+
+	// ResourcesUsed are the resources used so far, from resources_used
+	ResourcesUsed map[string]string
+
+	// Extra holds the attributes without a field, keyed by name, or
+	// name.resource for attributes with a resource
+	Extra map[string]string
+}
+
+// attribError reports an attribute value which couldn't be decoded
+func attribError(object string, a Attrib, err error) error {
+	return fmt.Errorf("pbs: %s: bad value %q for %s: %w", object, a.Value, extraKey(a), err)
+}
+
+// parseEpoch parses a time as seconds since the epoch
+func parseEpoch(s string) (time.Time, error) {
+	secs, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(secs, 0), nil
+}
+
+// DecodeJob decodes a job's status
+func DecodeJob(b BatchStatus) (Job, error) {
+	job := Job{ID: b.Name}
+	for _, a := range b.Attributes {
+		var t *time.Time
+		switch a.Name {
+		case ATTR_N:
+			job.Name = a.Value
+		case ATTR_owner:
+			job.Owner = a.Value
+		case ATTR_state:
+			state, err := ParseJobState(a.Value)
+			if err != nil {
+				return job, attribError(b.Name, a, err)
+			}
+			job.State = state
+		case ATTR_substate:
+			sub, err := ParseJobSubstate(a.Value)
+			if err != nil {
+				return job, attribError(b.Name, a, err)
+			}
+			job.Substate = sub
+		case ATTR_queue:
+			job.Queue = a.Value
+		case ATTR_server:
+			job.Server = a.Value
+		case ATTR_exechost:
+			job.ExecHost = a.Value
+		case ATTR_ctime:
+			t = &job.Ctime
+		case ATTR_mtime:
+			t = &job.Mtime
+		case ATTR_qtime:
+			t = &job.Qtime
+		case ATTR_etime:
+			t = &job.Etime
+		case ATTR_start_time:
+			t = &job.StartTime
+		case ATTR_exitstat:
+			n, err := strconv.Atoi(a.Value)
+			if err != nil {
+				return job, attribError(b.Name, a, err)
+			}
+			job.ExitStatus = n
+		case ATTR_l:
+			setMapValue(&job.Resources, a.Resource, a.Value)
+		case ATTR_used:
+			setMapValue(&job.ResourcesUsed, a.Resource, a.Value)
+		default:
+			setMapValue(&job.Extra, extraKey(a), a.Value)
+		}
+
+		if t != nil {
+			v, err := parseEpoch(a.Value)
+			if err != nil {
+				return job, attribError(b.Name, a, err)
+			}
+			*t = v
+		}
+	}
+	return job, nil
+}
+
+// DecodeJobs decodes the status of each job in batch
+func DecodeJobs(batch []BatchStatus) ([]Job, error) {
+	jobs := make([]Job, 0, len(batch))
+	for _, b := range batch {
+		job, err := DecodeJob(b)
+		if err != nil {
+			return jobs, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// StatJobs returns the status of the job id, or of all jobs if id is
+// empty, as with Client.StatJob
+func StatJobs(c Client, id string, extend string) ([]Job, error) {
+	batch, err := c.StatJob(id, nil, extend)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeJobs(batch)
+}
+
+// SelStatJobs returns the status of the jobs matching criteria, as with
+// Client.SelStat
+func SelStatJobs(c Client, criteria []Attrib, extend string) ([]Job, error) {
+	batch, err := c.SelStat(criteria, extend)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeJobs(batch)
+}
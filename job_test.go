@@ -0,0 +1,81 @@
+package pbs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStatJobs(t *testing.T) {
+	s := NewFakeServer("fake")
+	s.AddNode("node01", 2)
+	client := connectFake(t, s)
+	submitted := s.Now()
+
+	id, err := client.Submit([]Attrib{
+		{Name: ATTR_N, Value: "typed"},
+		{Name: ATTR_l, Resource: "walltime", Value: "01:00:00"},
+		{Name: ATTR_A, Value: "project"},
+	}, "test.sh", "", "")
+	if err != nil {
+		t.Fatalf("Job submission failed: %s\n", err)
+	}
+	s.Advance(30 * time.Second)
+	s.Finish(id, 3)
+
+	jobs, err := StatJobs(client, id, "")
+	if err != nil {
+		t.Fatalf("StatJobs failed: %s\n", err)
+	}
+	if len(jobs) != 1 {
+		t.Fatalf("StatJobs returned %d jobs\n", len(jobs))
+	}
+	job := jobs[0]
+
+	if job.ID != id || job.Name != "typed" || job.State != StateComplete || job.Queue != "batch" || job.Server != "fake" {
+		t.Errorf("Decoded job %+v\n", job)
+	}
+	if job.ExecHost != "node01/0" || job.ExitStatus != 3 {
+		t.Errorf("Job ran on %q and exited with %d\n", job.ExecHost, job.ExitStatus)
+	}
+	if !job.Ctime.Equal(submitted.Truncate(time.Second)) || job.StartTime.Before(job.Ctime) {
+		t.Errorf("Job created at %s and started at %s\n", job.Ctime, job.StartTime)
+	}
+	if job.Resources["walltime"] != "01:00:00" {
+		t.Errorf("Requested resources %v\n", job.Resources)
+	}
+	if job.ResourcesUsed["walltime"] != "00:00:30" {
+		t.Errorf("Used resources %v\n", job.ResourcesUsed)
+	}
+	if job.Substate != SubstateComplete {
+		t.Errorf("Job substate is %s\n", job.Substate)
+	}
+	if job.Extra[ATTR_A] != "project" {
+		t.Errorf("Extra attributes %v\n", job.Extra)
+	}
+
+	jobs, err = SelStatJobs(client, []Attrib{{Name: ATTR_N, Value: "typed", Op: EQ}}, "")
+	if err != nil || len(jobs) != 1 || jobs[0].ID != id {
+		t.Errorf("SelStatJobs returned %v, %v\n", jobs, err)
+	}
+}
+
+func TestDecodeJobErrors(t *testing.T) {
+	for _, a := range []Attrib{
+		{Name: ATTR_ctime, Value: "yesterday"},
+		{Name: ATTR_exitstat, Value: "-"},
+		{Name: ATTR_state, Value: "X"},
+		{Name: ATTR_substate, Value: "running"},
+	} {
+		if _, err := DecodeJob(BatchStatus{Name: "1.server", Attributes: []Attrib{a}}); err == nil {
+			t.Errorf("Decoding %s=%q didn't fail\n", a.Name, a.Value)
+		}
+	}
+
+	job, err := DecodeJob(BatchStatus{Name: "1.server", Attributes: []Attrib{
+		{Name: "Variable_List", Value: "HOME=/home/user"},
+		{Name: "custom", Resource: "res", Value: "x"},
+	}})
+	if err != nil || job.Extra["Variable_List"] != "HOME=/home/user" || job.Extra["custom.res"] != "x" {
+		t.Errorf("Decoded %+v, %v\n", job, err)
+	}
+}
@@ -0,0 +1,146 @@
+package pbs
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// JobID is a job identifier, "seq[index].server". The server is left off
+// short IDs, such as "1234", and the index is on the IDs of job arrays,
+// "1234[]", and of their elements, "1234[7]".
+type JobID struct {
+	Seq    uint64
+	Server string
+	// Array is true for job arrays and their elements
+	Array bool
+	// ArrayIndex is the index of an array element, or -1 for the array
+	ArrayIndex int
+}
+
+// ParseJobID parses a job ID
+func ParseJobID(s string) (JobID, error) {
+	invalid := errors.New("pbs: invalid job ID " + strconv.Quote(s))
+
+	var id JobID
+	rest, server, _ := strings.Cut(s, ".")
+	if strings.HasSuffix(rest, "]") {
+		var index string
+		var ok bool
+		rest, index, ok = strings.Cut(strings.TrimSuffix(rest, "]"), "[")
+		if !ok {
+			return id, invalid
+		}
+		id.Array, id.ArrayIndex = true, -1
+		if index != "" {
+			n, err := strconv.ParseUint(index, 10, 31)
+			if err != nil {
+				return id, invalid
+			}
+			id.ArrayIndex = int(n)
+		}
+	}
+	seq, err := strconv.ParseUint(rest, 10, 64)
+	if err != nil || strings.HasPrefix(rest, "+") {
+		return id, invalid
+	}
+	if strings.Contains(s, ".") && server == "" {
+		return id, invalid
+	}
+	id.Seq, id.Server = seq, server
+	return id, nil
+}
+
+// String formats id as it is given to Pbs_deljob and friends
+func (id JobID) String() string {
+	s := strconv.FormatUint(id.Seq, 10)
+	if id.Array {
+		s += "["
+		if id.ArrayIndex >= 0 {
+			s += strconv.Itoa(id.ArrayIndex)
+		}
+		s += "]"
+	}
+	if id.Server != "" {
+		s += "." + id.Server
+	}
+	return s
+}
+
+// IsArrayParent reports whether id is for a whole job array, "1234[]"
+func (id JobID) IsArrayParent() bool {
+	return id.Array && id.ArrayIndex < 0
+}
+
+// Parent returns the ID of the array which id is an element of, or id itself
+func (id JobID) Parent() JobID {
+	if id.Array {
+		id.ArrayIndex = -1
+	}
+	return id
+}
+
+// Normalize returns id with its server filled in: if id has no server it
+// gets server, and if its server is the short form of server, e.g.
+// "torque" for "torque.example.com", it's replaced by server. An empty
+// server means Pbs_default().
+func (id JobID) Normalize(server string) JobID {
+	if server == "" {
+		server = Pbs_default()
+	}
+	if id.Server == "" || strings.HasPrefix(server, id.Server+".") {
+		id.Server = server
+	}
+	return id
+}
+
+// Compare returns -1, 0 or 1 as id sorts before, with or after other. IDs
+// are ordered by sequence number, then with arrays after plain jobs and the
+// array's elements after it in index order, and then by server.
+func (id JobID) Compare(other JobID) int {
+	switch {
+	case id.Seq != other.Seq:
+		return compareOrdered(id.Seq, other.Seq)
+	case id.Array != other.Array:
+		if id.Array {
+			return 1
+		}
+		return -1
+	case id.ArrayIndex != other.ArrayIndex:
+		return compareOrdered(id.ArrayIndex, other.ArrayIndex)
+	}
+	return strings.Compare(id.Server, other.Server)
+}
+
+func compareOrdered[T uint64 | int](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// SortJobIDs sorts ids into the order of JobID.Compare
+func SortJobIDs(ids []JobID) {
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i].Compare(ids[j]) < 0
+	})
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (id JobID) MarshalText() ([]byte, error) {
+	return []byte(id.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (id *JobID) UnmarshalText(text []byte) error {
+	v, err := ParseJobID(string(text))
+	if err != nil {
+		return err
+	}
+	*id = v
+	return nil
+}
@@ -0,0 +1,77 @@
+package pbs
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestJobID(t *testing.T) {
+	tests := []struct {
+		s  string
+		id JobID
+	}{
+		{"1234", JobID{Seq: 1234}},
+		{"1234.server.example.com", JobID{Seq: 1234, Server: "server.example.com"}},
+		{"1234[7].server", JobID{Seq: 1234, Server: "server", Array: true, ArrayIndex: 7}},
+		{"1234[]", JobID{Seq: 1234, Array: true, ArrayIndex: -1}},
+		{"1234[0]", JobID{Seq: 1234, Array: true, ArrayIndex: 0}},
+	}
+	for _, test := range tests {
+		id, err := ParseJobID(test.s)
+		if err != nil || id != test.id {
+			t.Errorf("ParseJobID(%q) returned %+v, %v\n", test.s, id, err)
+		}
+		if id.String() != test.s {
+			t.Errorf("%+v formatted as %q, expected %q\n", id, id.String(), test.s)
+		}
+	}
+	for _, bad := range []string{"", "server", "12a.server", "1234.", "-1", "+1", "1234[", "1234]", "1234[x]", "1234[-1]", "[1]"} {
+		if id, err := ParseJobID(bad); err == nil {
+			t.Errorf("ParseJobID(%q) returned %+v\n", bad, id)
+		}
+	}
+
+	id, _ := ParseJobID("1234[]")
+	if !id.IsArrayParent() {
+		t.Errorf("%s isn't an array parent\n", id)
+	}
+	id, _ = ParseJobID("1234[3].torque")
+	if id.IsArrayParent() || id.Parent().String() != "1234[].torque" {
+		t.Errorf("%s has parent %s\n", id, id.Parent())
+	}
+}
+
+func TestJobIDNormalize(t *testing.T) {
+	for s, expected := range map[string]string{
+		"1234":                    "1234.torque.example.com",
+		"1234[2].torque":          "1234[2].torque.example.com",
+		"1234.torque.example.com": "1234.torque.example.com",
+		"1234.other":              "1234.other",
+		"1234.torque.example.org": "1234.torque.example.org",
+	} {
+		id, _ := ParseJobID(s)
+		if got := id.Normalize("torque.example.com").String(); got != expected {
+			t.Errorf("%s normalized to %s, expected %s\n", s, got, expected)
+		}
+	}
+}
+
+func TestSortJobIDs(t *testing.T) {
+	var ids []JobID
+	for _, s := range []string{"10.b", "9.a", "100[].a", "100[10].a", "100.a", "100[2].a", "10.a"} {
+		id, err := ParseJobID(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	SortJobIDs(ids)
+	var got []string
+	for _, id := range ids {
+		got = append(got, id.String())
+	}
+	expected := []string{"9.a", "10.a", "10.b", "100.a", "100[].a", "100[2].a", "100[10].a"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("sorted IDs are %v, expected %v\n", got, expected)
+	}
+}
@@ -0,0 +1,202 @@
+package pbs
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// JobState is a job's state, the letter in its job_state attribute
+type JobState byte
+
+// Job states
+const (
+	StateTransit   JobState = 'T'
+	StateQueued    JobState = 'Q'
+	StateHeld      JobState = 'H'
+	StateWaiting   JobState = 'W'
+	StateRunning   JobState = 'R'
+	StateExiting   JobState = 'E'
+	StateComplete  JobState = 'C'
+	StateSuspended JobState = 'S'
+)
+
+var jobStateNames = map[JobState]string{
+	StateTransit:   "Transit",
+	StateQueued:    "Queued",
+	StateHeld:      "Held",
+	StateWaiting:   "Waiting",
+	StateRunning:   "Running",
+	StateExiting:   "Exiting",
+	StateComplete:  "Complete",
+	StateSuspended: "Suspended",
+}
+
+// ParseJobState parses a job state, either the letter used by job_state or
+// the name, in any case
+func ParseJobState(s string) (JobState, error) {
+	if len(s) == 1 {
+		if state := JobState(s[0]); jobStateNames[state] != "" {
+			return state, nil
+		}
+	}
+	for state, name := range jobStateNames {
+		if strings.EqualFold(s, name) {
+			return state, nil
+		}
+	}
+	return 0, errors.New("pbs: unknown job state " + strconv.Quote(s))
+}
+
+// String returns the name of the state, e.g. "Queued"
+func (s JobState) String() string {
+	if name, ok := jobStateNames[s]; ok {
+		return name
+	}
+	return "JobState(" + strconv.Quote(string(rune(s))) + ")"
+}
+
+// Letter returns the letter used for s by job_state, e.g. "Q"
+func (s JobState) Letter() string {
+	return string(rune(s))
+}
+
+// IsTerminal reports whether s is a state jobs don't leave
+func (s JobState) IsTerminal() bool {
+	return s == StateComplete
+}
+
+// IsActive reports whether a job in state s has been started on its
+// nodes, and is holding them
+func (s JobState) IsActive() bool {
+	return s == StateRunning || s == StateExiting || s == StateSuspended
+}
+
+func (s JobState) MarshalText() ([]byte, error) {
+	return []byte(s.Letter()), nil
+}
+
+func (s *JobState) UnmarshalText(text []byte) error {
+	state, err := ParseJobState(string(text))
+	if err != nil {
+		return err
+	}
+	*s = state
+	return nil
+}
+
+// JobSubstate is the detailed state of a job, from its substate attribute,
+// with the values of TORQUE's JOB_SUBSTATE_* constants
+type JobSubstate int
+
+// Job substates
+const (
+	SubstateTransitIn        JobSubstate = 0
+	SubstateTransitInCommit  JobSubstate = 1
+	SubstateTransitOut       JobSubstate = 2
+	SubstateTransitOutCommit JobSubstate = 3
+	SubstateQueued           JobSubstate = 10
+	SubstatePrestageIn       JobSubstate = 11
+	SubstateSyncRes          JobSubstate = 13
+	SubstateStageIn          JobSubstate = 14
+	SubstateStageGo          JobSubstate = 15
+	SubstateStageComplete    JobSubstate = 16
+	SubstateHeld             JobSubstate = 20
+	SubstateSyncHold         JobSubstate = 21
+	SubstateDependHold       JobSubstate = 22
+	SubstateWaiting          JobSubstate = 30
+	SubstateStageFail        JobSubstate = 37
+	SubstatePrerun           JobSubstate = 40
+	SubstateStarting         JobSubstate = 41
+	SubstateRunning          JobSubstate = 42
+	SubstateSuspend          JobSubstate = 43
+	SubstateExiting          JobSubstate = 50
+	SubstateStageOut         JobSubstate = 51
+	SubstateStageDelete      JobSubstate = 52
+	SubstateExited           JobSubstate = 53
+	SubstateAbort            JobSubstate = 54
+	SubstateNoTermRequeue    JobSubstate = 55
+	SubstatePreObit          JobSubstate = 57
+	SubstateObit             JobSubstate = 58
+	SubstateComplete         JobSubstate = 59
+	SubstateRerun            JobSubstate = 60
+	SubstateRerun1           JobSubstate = 61
+	SubstateRerun2           JobSubstate = 62
+	SubstateRerun3           JobSubstate = 63
+	SubstateReturnStd        JobSubstate = 70
+)
+
+var jobSubstates = map[JobSubstate]struct {
+	state JobState
+	text  string
+}{
+	SubstateTransitIn:        {StateTransit, "transit in"},
+	SubstateTransitInCommit:  {StateTransit, "transit in, ready to commit"},
+	SubstateTransitOut:       {StateTransit, "transit out"},
+	SubstateTransitOutCommit: {StateTransit, "transit out, ready to commit"},
+	SubstateQueued:           {StateQueued, "queued"},
+	SubstatePrestageIn:       {StateQueued, "queued, files to stage in"},
+	SubstateSyncRes:          {StateQueued, "waiting for synchronized start"},
+	SubstateStageIn:          {StateQueued, "staging in"},
+	SubstateStageGo:          {StateQueued, "staging in before running"},
+	SubstateStageComplete:    {StateQueued, "staged in"},
+	SubstateHeld:             {StateHeld, "held"},
+	SubstateSyncHold:         {StateHeld, "held for synchronized start"},
+	SubstateDependHold:       {StateHeld, "held on dependency"},
+	SubstateWaiting:          {StateWaiting, "waiting for execution time"},
+	SubstateStageFail:        {StateHeld, "held, stage in failed"},
+	SubstatePrerun:           {StateRunning, "sent to MOM"},
+	SubstateStarting:         {StateRunning, "starting"},
+	SubstateRunning:          {StateRunning, "running"},
+	SubstateSuspend:          {StateSuspended, "suspended"},
+	SubstateExiting:          {StateExiting, "exiting"},
+	SubstateStageOut:         {StateExiting, "staging out"},
+	SubstateStageDelete:      {StateExiting, "deleting staged files"},
+	SubstateExited:           {StateExiting, "exited"},
+	SubstateAbort:            {StateExiting, "aborting"},
+	SubstateNoTermRequeue:    {StateExiting, "requeuing without termination"},
+	SubstatePreObit:          {StateExiting, "exiting, running epilogue"},
+	SubstateObit:             {StateExiting, "exiting after epilogue"},
+	SubstateComplete:         {StateComplete, "complete"},
+	SubstateRerun:            {StateExiting, "rerunning, recovering output"},
+	SubstateRerun1:           {StateExiting, "rerunning, staging out"},
+	SubstateRerun2:           {StateExiting, "rerunning, deleting files"},
+	SubstateRerun3:           {StateExiting, "rerunning, deleting job on MOM"},
+	SubstateReturnStd:        {StateQueued, "requeued with checkpoint"},
+}
+
+// ParseJobSubstate parses the number in a substate attribute
+func ParseJobSubstate(s string) (JobSubstate, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, errors.New("pbs: invalid job substate " + strconv.Quote(s))
+	}
+	return JobSubstate(n), nil
+}
+
+// String describes the substate, e.g. "staging in"
+func (s JobSubstate) String() string {
+	if sub, ok := jobSubstates[s]; ok {
+		return sub.text
+	}
+	return "substate " + strconv.Itoa(int(s))
+}
+
+// State returns the state which the substate is part of, or zero for an
+// unknown substate
+func (s JobSubstate) State() JobState {
+	return jobSubstates[s].state
+}
+
+func (s JobSubstate) MarshalText() ([]byte, error) {
+	return []byte(strconv.Itoa(int(s))), nil
+}
+
+func (s *JobSubstate) UnmarshalText(text []byte) error {
+	sub, err := ParseJobSubstate(string(text))
+	if err != nil {
+		return err
+	}
+	*s = sub
+	return nil
+}
@@ -0,0 +1,64 @@
+package pbs
+
+import "testing"
+
+func TestJobState(t *testing.T) {
+	for _, s := range []string{"R", "Running", "running"} {
+		if state, err := ParseJobState(s); err != nil || state != StateRunning {
+			t.Errorf("ParseJobState(%q) returned %v, %v\n", s, state, err)
+		}
+	}
+	for _, s := range []string{"", "X", "QR", "Run"} {
+		if _, err := ParseJobState(s); err == nil {
+			t.Errorf("ParseJobState(%q) didn't fail\n", s)
+		}
+	}
+
+	if StateHeld.String() != "Held" || StateHeld.Letter() != "H" || JobState('X').String() != `JobState("X")` {
+		t.Errorf("Unexpected names %s, %s, %s\n", StateHeld, StateHeld.Letter(), JobState('X'))
+	}
+	if !StateComplete.IsTerminal() || StateExiting.IsTerminal() {
+		t.Errorf("IsTerminal is wrong\n")
+	}
+	if !StateRunning.IsActive() || !StateExiting.IsActive() || StateQueued.IsActive() || StateComplete.IsActive() {
+		t.Errorf("IsActive is wrong\n")
+	}
+}
+
+func TestJobSubstate(t *testing.T) {
+	sub, err := ParseJobSubstate("14")
+	if err != nil || sub != SubstateStageIn || sub.String() != "staging in" || sub.State() != StateQueued {
+		t.Errorf("Substate 14 is %d (%s) in %s, %v\n", sub, sub, sub.State(), err)
+	}
+	if SubstateObit.String() != "exiting after epilogue" || SubstateObit.State() != StateExiting {
+		t.Errorf("Substate 58 is %s in %s\n", SubstateObit, SubstateObit.State())
+	}
+	if JobSubstate(99).String() != "substate 99" || JobSubstate(99).State() != 0 {
+		t.Errorf("Unknown substate is %s\n", JobSubstate(99))
+	}
+	if _, err := ParseJobSubstate("R"); err == nil {
+		t.Errorf("ParseJobSubstate(\"R\") didn't fail\n")
+	}
+
+	// The fake's substates agree with its states
+	for state, sub := range fakeSubstates {
+		if sub.State() != state {
+			t.Errorf("Fake reports substate %s for state %s\n", sub, state)
+		}
+	}
+}
+
+func TestJobStateUnmarshal(t *testing.T) {
+	var v struct {
+		State    JobState    `pbs:"job_state"`
+		Substate JobSubstate `pbs:"substate"`
+	}
+	err := Unmarshal(BatchStatus{Attributes: []Attrib{{Name: ATTR_state, Value: "E"}, {Name: ATTR_substate, Value: "51"}}}, &v)
+	if err != nil || v.State != StateExiting || v.Substate != SubstateStageOut {
+		t.Errorf("Unmarshalled %+v, %v\n", v, err)
+	}
+	attribs, err := Marshal(v)
+	if err != nil || len(attribs) != 2 || attribs[0].Value != "E" || attribs[1].Value != "51" {
+		t.Errorf("Marshalled %+v, %v\n", attribs, err)
+	}
+}
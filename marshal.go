@@ -0,0 +1,375 @@
+package pbs
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Marshal and Unmarshal convert between structs and attributes using the
+// field tags:
+//
+//	type MyJob struct {
+//		ID       string            `pbs:",id"`
+//		Name     string            `pbs:"Job_Name"`
+//		Walltime time.Duration     `pbs:"Resource_List,walltime"`
+//		Mem      pbs.Size          `pbs:"Resource_List,mem"`
+//		Used     map[string]string `pbs:"resources_used"`
+//		Other    map[string]string `pbs:",extra"`
+//	}
+//
+// The tag is the attribute name, followed by the resource if there is one.
+// A map field without a resource holds all of the attribute's resources,
+// keyed by resource. The ",id" field holds the object's name, e.g. the job
+// ID, and the ",extra" map the attributes without a field, keyed by name or
+// name.resource. Fields without a tag are ignored, except for embedded
+// structs, whose fields are used.
+//
+// Fields can be strings, bools ("True" or "False"), integers, floats,
+// []string (comma separated), time.Duration (walltime format, [[HH:]MM:]SS),
+// time.Time (seconds since the epoch), types implementing
+// encoding.TextMarshaler and encoding.TextUnmarshaler, such as Size, and
+// pointers to these.
+
+// AttribError reports an attribute which couldn't be converted to or from a
+// struct field
+type AttribError struct {
+	// Op is "marshal" or "unmarshal"
+	Op     string
+	Attrib string
+	Value  string
+	Field  string
+	Type   reflect.Type
+	Err    error
+}
+
+func (e *AttribError) Error() string {
+	var s string
+	if e.Op == "marshal" {
+		s = fmt.Sprintf("pbs: cannot marshal field %s of type %s as %s", e.Field, e.Type, e.Attrib)
+	} else {
+		s = fmt.Sprintf("pbs: cannot unmarshal %s value %q into field %s of type %s", e.Attrib, e.Value, e.Field, e.Type)
+	}
+	if e.Err != nil {
+		s += ": " + e.Err.Error()
+	}
+	return s
+}
+
+func (e *AttribError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	errUnsupportedType = errors.New("unsupported type")
+
+	durationType = reflect.TypeOf(time.Duration(0))
+	timeType     = reflect.TypeOf(time.Time{})
+)
+
+// tagField is a struct field with a pbs tag
+type tagField struct {
+	index    []int
+	name     string
+	resource string
+	id       bool
+	extra    bool
+}
+
+func (f tagField) attrib() string {
+	return extraKey(Attrib{Name: f.name, Resource: f.resource})
+}
+
+// tagFields returns the tagged fields of t, including those of embedded
+// structs
+func tagFields(t reflect.Type, index []int) []tagField {
+	var fields []tagField
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		idx := append(append([]int(nil), index...), i)
+
+		tag, ok := sf.Tag.Lookup("pbs")
+		if !ok {
+			if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+				fields = append(fields, tagFields(sf.Type, idx)...)
+			}
+			continue
+		}
+		if tag == "-" || !sf.IsExported() {
+			continue
+		}
+
+		f := tagField{index: idx}
+		name, rest, _ := strings.Cut(tag, ",")
+		switch {
+		case name == "" && rest == "id":
+			f.id = true
+		case name == "" && rest == "extra":
+			f.extra = true
+		default:
+			f.name, f.resource = name, rest
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// structValue returns the struct v points to, or is
+func structValue(v interface{}, op string) (reflect.Value, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	} else if op == "Unmarshal" {
+		return rv, fmt.Errorf("pbs: Unmarshal requires a non-nil pointer to a struct, not %T", v)
+	}
+	if rv.Kind() != reflect.Struct {
+		return rv, fmt.Errorf("pbs: %s requires a struct, not %T", op, v)
+	}
+	return rv, nil
+}
+
+// Unmarshal stores the attributes of b in the struct v points to
+func Unmarshal(b BatchStatus, v interface{}) error {
+	rv, err := structValue(v, "Unmarshal")
+	if err != nil {
+		return err
+	}
+
+	fields := tagFields(rv.Type(), nil)
+	exact := map[string]tagField{}
+	maps := map[string]tagField{}
+	var extra *tagField
+	for i, f := range fields {
+		fv := rv.FieldByIndex(f.index)
+		switch {
+		case f.id:
+			if err := setField(fv, b.Name); err != nil {
+				return unmarshalError(rv, f, Attrib{Value: b.Name}, err)
+			}
+		case f.extra:
+			extra = &fields[i]
+		case f.resource == "" && fv.Kind() == reflect.Map:
+			maps[f.name] = f
+		default:
+			exact[f.attrib()] = f
+		}
+	}
+
+	for _, a := range b.Attributes {
+		if f, ok := exact[extraKey(a)]; ok {
+			fv := rv.FieldByIndex(f.index)
+			if err := setField(fv, a.Value); err != nil {
+				return unmarshalError(rv, f, a, err)
+			}
+		} else if f, ok := maps[a.Name]; ok {
+			if err := setMapField(rv.FieldByIndex(f.index), a.Resource, a.Value); err != nil {
+				return unmarshalError(rv, f, a, err)
+			}
+		} else if extra != nil {
+			if err := setMapField(rv.FieldByIndex(extra.index), extraKey(a), a.Value); err != nil {
+				return unmarshalError(rv, *extra, a, err)
+			}
+		}
+	}
+	return nil
+}
+
+func unmarshalError(rv reflect.Value, f tagField, a Attrib, err error) error {
+	sf := rv.Type().FieldByIndex(f.index)
+	return &AttribError{Op: "unmarshal", Attrib: extraKey(a), Value: a.Value, Field: sf.Name, Type: sf.Type, Err: err}
+}
+
+func setMapField(fv reflect.Value, key string, value string) error {
+	t := fv.Type()
+	if t.Kind() != reflect.Map || t.Key().Kind() != reflect.String {
+		return errUnsupportedType
+	}
+	if fv.IsNil() {
+		fv.Set(reflect.MakeMap(t))
+	}
+	elem := reflect.New(t.Elem()).Elem()
+	if err := setField(elem, value); err != nil {
+		return err
+	}
+	fv.SetMapIndex(reflect.ValueOf(key).Convert(t.Key()), elem)
+	return nil
+}
+
+// setField parses s into fv
+func setField(fv reflect.Value, s string) error {
+	if fv.Kind() == reflect.Ptr {
+		v := reflect.New(fv.Type().Elem())
+		if err := setField(v.Elem(), s); err != nil {
+			return err
+		}
+		fv.Set(v)
+		return nil
+	}
+
+	switch fv.Type() {
+	case durationType:
+		d, err := parseDuration(s)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(int64(d))
+		return nil
+	case timeType:
+		t, err := parseEpoch(s)
+		if err != nil {
+			return err
+		}
+		fv.Set(reflect.ValueOf(t))
+		return nil
+	}
+	if u, ok := fv.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(s)
+	case reflect.Bool:
+		b, err := parseAttribBool(s)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+	case reflect.Slice:
+		if fv.Type().Elem().Kind() != reflect.String {
+			return errUnsupportedType
+		}
+		list := splitList(s)
+		slice := reflect.MakeSlice(fv.Type(), len(list), len(list))
+		for i, item := range list {
+			slice.Index(i).SetString(item)
+		}
+		fv.Set(slice)
+	default:
+		return errUnsupportedType
+	}
+	return nil
+}
+
+// Marshal returns the attributes for the tagged fields of v, a struct or a
+// pointer to one. Fields with zero values are left out, pointers can be
+// used for values which must be sent even when they are zero. The ",id"
+// field is ignored.
+func Marshal(v interface{}) ([]Attrib, error) {
+	rv, err := structValue(v, "Marshal")
+	if err != nil {
+		return nil, err
+	}
+
+	var attribs []Attrib
+	for _, f := range tagFields(rv.Type(), nil) {
+		fv := rv.FieldByIndex(f.index)
+		if f.id || fv.IsZero() {
+			continue
+		}
+
+		if f.extra || (f.resource == "" && fv.Kind() == reflect.Map) {
+			if fv.Kind() != reflect.Map || fv.Type().Key().Kind() != reflect.String {
+				return nil, marshalError(rv, f, errUnsupportedType)
+			}
+			keys := make([]string, 0, fv.Len())
+			for _, k := range fv.MapKeys() {
+				keys = append(keys, k.String())
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				s, err := formatField(fv.MapIndex(reflect.ValueOf(k).Convert(fv.Type().Key())))
+				if err != nil {
+					return nil, marshalError(rv, f, err)
+				}
+				a := Attrib{Name: f.name, Resource: k, Value: s}
+				if f.extra {
+					a.Name, a.Resource, _ = strings.Cut(k, ".")
+				}
+				attribs = append(attribs, a)
+			}
+			continue
+		}
+
+		s, err := formatField(fv)
+		if err != nil {
+			return nil, marshalError(rv, f, err)
+		}
+		attribs = append(attribs, Attrib{Name: f.name, Resource: f.resource, Value: s})
+	}
+	return attribs, nil
+}
+
+func marshalError(rv reflect.Value, f tagField, err error) error {
+	sf := rv.Type().FieldByIndex(f.index)
+	return &AttribError{Op: "marshal", Attrib: f.attrib(), Field: sf.Name, Type: sf.Type, Err: err}
+}
+
+// formatField formats fv as an attribute value
+func formatField(fv reflect.Value) (string, error) {
+	if fv.Kind() == reflect.Ptr {
+		return formatField(fv.Elem())
+	}
+
+	switch fv.Type() {
+	case durationType:
+		return hms(time.Duration(fv.Int())), nil
+	case timeType:
+		return epoch(fv.Interface().(time.Time)), nil
+	}
+	if m, ok := fv.Interface().(encoding.TextMarshaler); ok {
+		text, err := m.MarshalText()
+		return string(text), err
+	}
+	if fv.CanAddr() {
+		if m, ok := fv.Addr().Interface().(encoding.TextMarshaler); ok {
+			text, err := m.MarshalText()
+			return string(text), err
+		}
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		return fv.String(), nil
+	case reflect.Bool:
+		return boolValue(fv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(fv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(fv.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(fv.Float(), 'g', -1, fv.Type().Bits()), nil
+	case reflect.Slice:
+		if fv.Type().Elem().Kind() == reflect.String {
+			list := make([]string, fv.Len())
+			for i := range list {
+				list[i] = fv.Index(i).String()
+			}
+			return strings.Join(list, ","), nil
+		}
+	}
+	return "", errUnsupportedType
+}
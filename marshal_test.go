@@ -0,0 +1,174 @@
+package pbs
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type siteJob struct {
+	ID        string            `pbs:",id"`
+	Name      string            `pbs:"Job_Name"`
+	Rerunable bool              `pbs:"Rerunable"`
+	Priority  int               `pbs:"Priority"`
+	Walltime  time.Duration     `pbs:"Resource_List,walltime"`
+	Mem       Size              `pbs:"Resource_List,mem"`
+	Nodes     *string           `pbs:"Resource_List,nodes"`
+	Ctime     time.Time         `pbs:"ctime"`
+	Groups    []string          `pbs:"group_list"`
+	Used      map[string]string `pbs:"resources_used"`
+	Load      float64           `pbs:"custom_load"`
+	Extra     map[string]string `pbs:",extra"`
+	Ignored   string
+}
+
+func TestUnmarshal(t *testing.T) {
+	b := BatchStatus{Name: "1.server", Attributes: []Attrib{
+		{Name: ATTR_N, Value: "site"},
+		{Name: ATTR_r, Value: "True"},
+		{Name: ATTR_p, Value: "-10"},
+		{Name: ATTR_l, Resource: "walltime", Value: "01:30:00"},
+		{Name: ATTR_l, Resource: "mem", Value: "16gb"},
+		{Name: ATTR_l, Resource: "nodes", Value: "2:ppn=4"},
+		{Name: ATTR_l, Resource: "software", Value: "matlab"},
+		{Name: ATTR_ctime, Value: "1400000000"},
+		{Name: ATTR_g, Value: "users,staff"},
+		{Name: ATTR_used, Resource: "cput", Value: "00:01:00"},
+		{Name: ATTR_used, Resource: "mem", Value: "1024kb"},
+		{Name: "custom_load", Value: "0.75"},
+		{Name: "Variable_List", Value: "HOME=/home/user"},
+	}}
+
+	var job siteJob
+	if err := Unmarshal(b, &job); err != nil {
+		t.Fatalf("Unmarshal failed: %s\n", err)
+	}
+	nodes := "2:ppn=4"
+	expected := siteJob{
+		ID:        "1.server",
+		Name:      "site",
+		Rerunable: true,
+		Priority:  -10,
+		Walltime:  90 * time.Minute,
+		Mem:       16 * Gigabyte,
+		Nodes:     &nodes,
+		Ctime:     time.Unix(1400000000, 0),
+		Groups:    []string{"users", "staff"},
+		Used:      map[string]string{"cput": "00:01:00", "mem": "1024kb"},
+		Load:      0.75,
+		Extra:     map[string]string{"Resource_List.software": "matlab", "Variable_List": "HOME=/home/user"},
+	}
+	if !reflect.DeepEqual(job, expected) {
+		t.Errorf("Unmarshalled %+v\nexpected %+v\n", job, expected)
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	nodes := "1:ppn=2"
+	job := siteJob{
+		ID:       "ignored",
+		Name:     "site",
+		Walltime: 36*time.Hour + 5*time.Second,
+		Mem:      512 * Megabyte,
+		Nodes:    &nodes,
+		Groups:   []string{"a", "b"},
+		Used:     map[string]string{"mem": "1kb", "cput": "0"},
+		Extra:    map[string]string{"Resource_List.software": "matlab", "Account_Name": "proj"},
+	}
+
+	attribs, err := Marshal(job)
+	if err != nil {
+		t.Fatalf("Marshal failed: %s\n", err)
+	}
+	expected := []Attrib{
+		{Name: ATTR_N, Value: "site"},
+		{Name: ATTR_l, Resource: "walltime", Value: "36:00:05"},
+		{Name: ATTR_l, Resource: "mem", Value: "512mb"},
+		{Name: ATTR_l, Resource: "nodes", Value: "1:ppn=2"},
+		{Name: ATTR_g, Value: "a,b"},
+		{Name: ATTR_used, Resource: "cput", Value: "0"},
+		{Name: ATTR_used, Resource: "mem", Value: "1kb"},
+		{Name: ATTR_A, Value: "proj"},
+		{Name: ATTR_l, Resource: "software", Value: "matlab"},
+	}
+	if !reflect.DeepEqual(attribs, expected) {
+		t.Errorf("Marshalled %+v\nexpected %+v\n", attribs, expected)
+	}
+
+	// Marshal then Unmarshal returns the same values
+	var again siteJob
+	if err := Unmarshal(BatchStatus{Name: "ignored", Attributes: attribs}, &again); err != nil {
+		t.Fatalf("Unmarshal failed: %s\n", err)
+	}
+	if !reflect.DeepEqual(again, job) {
+		t.Errorf("Round trip gave %+v\nexpected %+v\n", again, job)
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	tests := []Attrib{
+		{Name: ATTR_r, Value: "maybe"},
+		{Name: ATTR_p, Value: "high"},
+		{Name: ATTR_l, Resource: "walltime", Value: "1:2:3:4"},
+		{Name: ATTR_l, Resource: "mem", Value: "lots"},
+		{Name: ATTR_ctime, Value: "yesterday"},
+	}
+	for _, a := range tests {
+		var job siteJob
+		err := Unmarshal(BatchStatus{Attributes: []Attrib{a}}, &job)
+		var ae *AttribError
+		if !errors.As(err, &ae) || ae.Value != a.Value || ae.Field == "" {
+			t.Errorf("Unmarshalling %s=%q returned %v\n", a.Name, a.Value, err)
+		}
+	}
+
+	var job siteJob
+	if err := Unmarshal(BatchStatus{}, job); err == nil {
+		t.Errorf("Unmarshal into a non-pointer didn't fail\n")
+	}
+	var bad struct {
+		C chan int `pbs:"Job_Name"`
+	}
+	if err := Unmarshal(BatchStatus{Attributes: []Attrib{{Name: ATTR_N, Value: "x"}}}, &bad); !errors.Is(err, errUnsupportedType) {
+		t.Errorf("Unmarshal into a channel returned %v\n", err)
+	}
+	bad.C = make(chan int)
+	if _, err := Marshal(bad); !errors.Is(err, errUnsupportedType) {
+		t.Errorf("Marshal of a channel returned %v\n", err)
+	}
+}
+
+func TestSize(t *testing.T) {
+	tests := []struct {
+		s    string
+		size Size
+		str  string
+	}{
+		{"0", 0, "0b"},
+		{"100", 100, "100b"},
+		{"100b", 100, "100b"},
+		{"1024kb", Megabyte, "1mb"},
+		{"16GB", 16 * Gigabyte, "16gb"},
+		{"4k", 4 * Kilobyte, "4kb"},
+		{"1536mb", 1536 * Megabyte, "1536mb"},
+		{"2tb", 2 * Terabyte, "2tb"},
+		{"1w", 8, "8b"},
+		{"64MW", 512 * Megabyte, "512mb"},
+		{"3kw", 24 * Kilobyte, "24kb"},
+	}
+	for _, test := range tests {
+		size, err := ParseSize(test.s)
+		if err != nil || size != test.size {
+			t.Errorf("ParseSize(%q) returned %d, %v\n", test.s, size, err)
+		}
+		if size.String() != test.str {
+			t.Errorf("%d formatted as %q, expected %q\n", size, size.String(), test.str)
+		}
+	}
+	for _, bad := range []string{"", "gb", "-1kb", "1.5gb", "10zb", "99999999pb", "w", "1bw", "2000000pw"} {
+		if _, err := ParseSize(bad); err == nil {
+			t.Errorf("ParseSize(%q) didn't fail\n", bad)
+		}
+	}
+}
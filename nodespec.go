@@ -0,0 +1,163 @@
+package pbs
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// NodeSpec is the value of the nodes resource, e.g. "2:ppn=8:gpus=1+bigmem01",
+// with a NodeRequest for each "+" separated part.
+type NodeSpec []NodeRequest
+
+// NodeRequest asks for Count nodes, or the node Host, with the given
+// processors per node, GPUs and properties. Other "name=value" parts, such as
+// mics, are kept in Options.
+type NodeRequest struct {
+	Count      int
+	Host       string
+	PPN        int
+	GPUs       int
+	Properties []string
+	Options    map[string]string
+}
+
+// ParseNodeSpec parses a nodes resource, with or without a leading "nodes=".
+// A part which starts with a number is a count of nodes, otherwise it is a
+// host name with a Count of 1.
+func ParseNodeSpec(s string) (NodeSpec, error) {
+	orig := s
+	s = strings.TrimPrefix(strings.TrimSpace(s), "nodes=")
+	if s == "" {
+		return nil, errors.New("pbs: empty node spec")
+	}
+
+	var spec NodeSpec
+	for _, part := range strings.Split(s, "+") {
+		req, err := parseNodeRequest(part)
+		if err != nil {
+			return nil, errors.New("pbs: invalid node spec " + strconv.Quote(orig) + ": " + err.Error())
+		}
+		spec = append(spec, req)
+	}
+	return spec, nil
+}
+
+func parseNodeRequest(s string) (NodeRequest, error) {
+	fields := strings.Split(s, ":")
+	if fields[0] == "" {
+		return NodeRequest{}, errors.New("missing node count or name")
+	}
+
+	var req NodeRequest
+	if fields[0][0] >= '0' && fields[0][0] <= '9' {
+		n, err := strconv.Atoi(fields[0])
+		if err != nil || n < 1 {
+			return req, errors.New("invalid node count " + strconv.Quote(fields[0]))
+		}
+		req.Count = n
+	} else {
+		req.Count, req.Host = 1, fields[0]
+	}
+
+	for _, field := range fields[1:] {
+		name, value, ok := strings.Cut(field, "=")
+		switch {
+		case field == "":
+			return req, errors.New("empty property")
+		case !ok:
+			req.Properties = append(req.Properties, field)
+		case name == "ppn" || name == "gpus":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return req, errors.New("invalid " + name + " " + strconv.Quote(value))
+			}
+			if name == "ppn" {
+				req.PPN = n
+			} else {
+				req.GPUs = n
+			}
+		default:
+			setMapValue(&req.Options, name, value)
+		}
+	}
+	return req, nil
+}
+
+// String formats the request, with ppn, gpus and the options before the
+// properties
+func (r NodeRequest) String() string {
+	var b strings.Builder
+	if r.Host != "" {
+		b.WriteString(r.Host)
+	} else {
+		b.WriteString(strconv.Itoa(r.Count))
+	}
+	if r.PPN > 0 {
+		b.WriteString(":ppn=" + strconv.Itoa(r.PPN))
+	}
+	if r.GPUs > 0 {
+		b.WriteString(":gpus=" + strconv.Itoa(r.GPUs))
+	}
+	names := make([]string, 0, len(r.Options))
+	for name := range r.Options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		b.WriteString(":" + name + "=" + r.Options[name])
+	}
+	for _, prop := range r.Properties {
+		b.WriteString(":" + prop)
+	}
+	return b.String()
+}
+
+// String formats s without the "nodes=" prefix, as it appears in
+// Resource_List.nodes
+func (s NodeSpec) String() string {
+	parts := make([]string, len(s))
+	for i, req := range s {
+		parts[i] = req.String()
+	}
+	return strings.Join(parts, "+")
+}
+
+// Nodes returns the number of nodes requested
+func (s NodeSpec) Nodes() int {
+	n := 0
+	for _, req := range s {
+		n += req.Count
+	}
+	return n
+}
+
+// Procs returns the number of processors requested, counting a node without
+// ppn as one
+func (s NodeSpec) Procs() int {
+	n := 0
+	for _, req := range s {
+		if req.PPN > 1 {
+			n += req.Count * req.PPN
+		} else {
+			n += req.Count
+		}
+	}
+	return n
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (s NodeSpec) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (s *NodeSpec) UnmarshalText(b []byte) error {
+	v, err := ParseNodeSpec(string(b))
+	if err != nil {
+		return err
+	}
+	*s = v
+	return nil
+}
@@ -0,0 +1,59 @@
+package pbs
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNodeSpec(t *testing.T) {
+	tests := []struct {
+		s     string
+		spec  NodeSpec
+		str   string
+		procs int
+	}{
+		{"1", NodeSpec{{Count: 1}}, "1", 1},
+		{"nodes=2:ppn=8", NodeSpec{{Count: 2, PPN: 8}}, "2:ppn=8", 16},
+		{
+			"2:ppn=4:gpus=1+node01:bigmem",
+			NodeSpec{{Count: 2, PPN: 4, GPUs: 1}, {Count: 1, Host: "node01", Properties: []string{"bigmem"}}},
+			"2:ppn=4:gpus=1+node01:bigmem",
+			9,
+		},
+		{
+			"4:ib:ppn=12:mics=2",
+			NodeSpec{{Count: 4, PPN: 12, Properties: []string{"ib"}, Options: map[string]string{"mics": "2"}}},
+			"4:ppn=12:mics=2:ib",
+			48,
+		},
+	}
+	for _, test := range tests {
+		spec, err := ParseNodeSpec(test.s)
+		if err != nil || !reflect.DeepEqual(spec, test.spec) {
+			t.Errorf("ParseNodeSpec(%q) returned %+v, %v\n", test.s, spec, err)
+			continue
+		}
+		if spec.String() != test.str {
+			t.Errorf("%q formatted as %q, expected %q\n", test.s, spec.String(), test.str)
+		}
+		if spec.Procs() != test.procs {
+			t.Errorf("%q has %d procs, expected %d\n", test.s, spec.Procs(), test.procs)
+		}
+	}
+	for _, bad := range []string{"", "nodes=", "0:ppn=2", "2:ppn=x", "1+", "1::fast", "2x:ppn=1"} {
+		if _, err := ParseNodeSpec(bad); err == nil {
+			t.Errorf("ParseNodeSpec(%q) didn't fail\n", bad)
+		}
+	}
+
+	var job struct {
+		Nodes NodeSpec `pbs:"Resource_List,nodes"`
+	}
+	attribs := []Attrib{{Name: "Resource_List", Resource: "nodes", Value: "3:ppn=2"}}
+	if err := Unmarshal(BatchStatus{Attributes: attribs}, &job); err != nil || job.Nodes.Nodes() != 3 {
+		t.Errorf("Unmarshal of nodes returned %+v, %v\n", job, err)
+	}
+	if got, err := Marshal(job); err != nil || !reflect.DeepEqual(got, attribs) {
+		t.Errorf("Marshal of nodes returned %+v, %v\n", got, err)
+	}
+}
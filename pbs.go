@@ -1,21 +1,5 @@
-// Package pbs provides an interface to the C-based TORQUE library.
-// The functions present in this package are a thin wrapper around these
-// library functions and as such the TORQUE library provides documentation on
-// their usage.
-//
-// The TORQUE library is not thread safe, particulary when it comes to
-// reporting errors, and therefore problems *might* arise if you use this
-// package with goroutines.
-//
-// The following functions have not yet been implemented:
-/*
-   pbs_alterjob      - untested
-   pbs_alterjobasync
-   pbs_manager       - untested
-   pbs_rescreserve
-   pbs_asyncrunjob
-   pbs_sigjobasync
-*/
+//go:build cgo && !nolibtorque
+
 package pbs
 
 /*
@@ -44,206 +28,216 @@ static void addStringToArray (char **array, char *str, unsigned int offset) {
 */
 import "C"
 import (
-	"errors"
 	"unsafe"
 )
 
-// BatchStatus represents the batch_status structure
-type BatchStatus struct {
-	Name       string
-	Text       string
-	Attributes []Attrib
-}
-
-// Attrib represents the attrl and attropl structures
-type Attrib struct {
-	Name     string
-	Resource string
-	Value    string
-	Op       Operator
-}
-
-// Manner defines how the server should be terminated
-type Manner int
-
-// Hold defines the type of job hold to place on a job
-type Hold string
-
-// MessageStream which output stream should be written to
-type MessageStream int
-
-// Operator defines types of logical comparator
-type Operator int
-
-type Command int
-
-type ObjectType int
-
-const (
-	SHUT_IMMEDIATE                 Manner        = C.SHUT_IMMEDIATE
-	SHUT_DELAY                     Manner        = C.SHUT_DELAY
-	USER_HOLD                      Hold          = C.USER_HOLD
-	OTHER_HOLD                     Hold          = C.OTHER_HOLD
-	SYSTEM_HOLD                    Hold          = C.SYSTEM_HOLD
-	MSG_ERR                        MessageStream = C.MSG_ERR
-	MSG_OUT                        MessageStream = C.MSG_OUT
-	MGR_CMD_CREATE                 Command       = C.MGR_CMD_CREATE
-	MGR_CMD_DELETE                 Command       = C.MGR_CMD_DELETE
-	MGR_CMD_SET                    Command       = C.MGR_CMD_SET
-	MGR_CMD_UNSET                  Command       = C.MGR_CMD_UNSET
-	MGR_CMD_LIST                   Command       = C.MGR_CMD_LIST
-	MGR_CMD_PRINT                  Command       = C.MGR_CMD_PRINT
-	MGR_CMD_ACTIVE                 Command       = C.MGR_CMD_ACTIVE
-	MGR_OBJ_NONE                   ObjectType    = C.MGR_OBJ_NONE
-	MGR_OBJ_SERVER                 ObjectType    = C.MGR_OBJ_SERVER
-	MGR_OBJ_QUEUE                  ObjectType    = C.MGR_OBJ_QUEUE
-	MGR_OBJ_JOB                    ObjectType    = C.MGR_OBJ_JOB
-	MGR_OBJ_NODE                   ObjectType    = C.MGR_OBJ_NODE
-	ATTR_a                         string        = C.ATTR_a
-	ATTR_c                         string        = C.ATTR_c
-	ATTR_e                         string        = C.ATTR_e
-	ATTR_f                         string        = C.ATTR_f
-	ATTR_g                         string        = C.ATTR_g
-	ATTR_h                         string        = C.ATTR_h
-	ATTR_j                         string        = C.ATTR_j
-	ATTR_k                         string        = C.ATTR_k
-	ATTR_l                         string        = C.ATTR_l
-	ATTR_m                         string        = C.ATTR_m
-	ATTR_o                         string        = C.ATTR_o
-	ATTR_p                         string        = C.ATTR_p
-	ATTR_q                         string        = C.ATTR_q
-	ATTR_r                         string        = C.ATTR_r
-	ATTR_t                         string        = C.ATTR_t
-	ATTR_array_id                  string        = C.ATTR_array_id
-	ATTR_u                         string        = C.ATTR_u
-	ATTR_v                         string        = C.ATTR_v
-	ATTR_A                         string        = C.ATTR_A
-	ATTR_args                      string        = C.ATTR_args
-	ATTR_M                         string        = C.ATTR_M
-	ATTR_N                         string        = C.ATTR_N
-	ATTR_S                         string        = C.ATTR_S
-	ATTR_depend                    string        = C.ATTR_depend
-	ATTR_inter                     string        = C.ATTR_inter
-	ATTR_stagein                   string        = C.ATTR_stagein
-	ATTR_stageout                  string        = C.ATTR_stageout
-	ATTR_jobtype                   string        = C.ATTR_jobtype
-	ATTR_submit_host               string        = C.ATTR_submit_host
-	ATTR_init_work_dir             string        = C.ATTR_init_work_dir
-	ATTR_ctime                     string        = C.ATTR_ctime
-	ATTR_exechost                  string        = C.ATTR_exechost
-	ATTR_execport                  string        = C.ATTR_execport
-	ATTR_mtime                     string        = C.ATTR_mtime
-	ATTR_qtime                     string        = C.ATTR_qtime
-	ATTR_session                   string        = C.ATTR_session
-	ATTR_euser                     string        = C.ATTR_euser
-	ATTR_egroup                    string        = C.ATTR_egroup
-	ATTR_hashname                  string        = C.ATTR_hashname
-	ATTR_hopcount                  string        = C.ATTR_hopcount
-	ATTR_security                  string        = C.ATTR_security
-	ATTR_sched_hint                string        = C.ATTR_sched_hint
-	ATTR_substate                  string        = C.ATTR_substate
-	ATTR_name                      string        = C.ATTR_name
-	ATTR_owner                     string        = C.ATTR_owner
-	ATTR_used                      string        = C.ATTR_used
-	ATTR_state                     string        = C.ATTR_state
-	ATTR_queue                     string        = C.ATTR_queue
-	ATTR_server                    string        = C.ATTR_server
-	ATTR_maxrun                    string        = C.ATTR_maxrun
-	ATTR_maxreport                 string        = C.ATTR_maxreport
-	ATTR_total                     string        = C.ATTR_total
-	ATTR_comment                   string        = C.ATTR_comment
-	ATTR_cookie                    string        = C.ATTR_cookie
-	ATTR_qrank                     string        = C.ATTR_qrank
-	ATTR_altid                     string        = C.ATTR_altid
-	ATTR_etime                     string        = C.ATTR_etime
-	ATTR_exitstat                  string        = C.ATTR_exitstat
-	ATTR_forwardx11                string        = C.ATTR_forwardx11
-	ATTR_submit_args               string        = C.ATTR_submit_args
-	ATTR_tokens                    string        = C.ATTR_tokens
-	ATTR_netcounter                string        = C.ATTR_netcounter
-	ATTR_umask                     string        = C.ATTR_umask
-	ATTR_start_time                string        = C.ATTR_start_time
-	ATTR_start_count               string        = C.ATTR_start_count
-	ATTR_checkpoint_dir            string        = C.ATTR_checkpoint_dir
-	ATTR_checkpoint_name           string        = C.ATTR_checkpoint_name
-	ATTR_checkpoint_time           string        = C.ATTR_checkpoint_time
-	ATTR_checkpoint_restart_status string        = C.ATTR_checkpoint_restart_status
-	ATTR_restart_name              string        = C.ATTR_restart_name
-	ATTR_comp_time                 string        = C.ATTR_comp_time
-	ATTR_reported                  string        = C.ATTR_reported
-	ATTR_intcmd                    string        = C.ATTR_intcmd
-	ATTR_P                         string        = C.ATTR_P
-	ATTR_node_exclusive            string        = C.ATTR_node_exclusive
-	ATTR_exec_gpus                 string        = C.ATTR_exec_gpus
-	ATTR_J                         string        = C.ATTR_J
-	SET                            Operator      = C.SET
-	UNSET                          Operator      = C.UNSET
-	INCR                           Operator      = C.INCR
-	DECR                           Operator      = C.DECR
-	EQ                             Operator      = C.EQ
-	NE                             Operator      = C.NE
-	GE                             Operator      = C.GE
-	GT                             Operator      = C.GT
-	LE                             Operator      = C.LE
-	LT                             Operator      = C.LT
-	DFLT                           Operator      = C.DFLT
-	MERGE                          Operator      = C.MERGE
-	INCR_OLD                       Operator      = C.INCR_OLD
-)
-
-func getLastError() error {
-	return errors.New(Pbs_strerror(int(C.pbs_errno)))
+// headerMismatches reports the constants from types.go whose values differ
+// from the TORQUE headers this package was compiled against
+func headerMismatches() []string {
+	var bad []string
+	for _, c := range []struct {
+		name      string
+		got, want interface{}
+	}{
+		{"SHUT_IMMEDIATE", int(SHUT_IMMEDIATE), int(C.SHUT_IMMEDIATE)},
+		{"SHUT_DELAY", int(SHUT_DELAY), int(C.SHUT_DELAY)},
+		{"USER_HOLD", string(USER_HOLD), C.USER_HOLD},
+		{"OTHER_HOLD", string(OTHER_HOLD), C.OTHER_HOLD},
+		{"SYSTEM_HOLD", string(SYSTEM_HOLD), C.SYSTEM_HOLD},
+		{"MSG_ERR", int(MSG_ERR), int(C.MSG_ERR)},
+		{"MSG_OUT", int(MSG_OUT), int(C.MSG_OUT)},
+		{"MGR_CMD_CREATE", int(MGR_CMD_CREATE), int(C.MGR_CMD_CREATE)},
+		{"MGR_CMD_DELETE", int(MGR_CMD_DELETE), int(C.MGR_CMD_DELETE)},
+		{"MGR_CMD_SET", int(MGR_CMD_SET), int(C.MGR_CMD_SET)},
+		{"MGR_CMD_UNSET", int(MGR_CMD_UNSET), int(C.MGR_CMD_UNSET)},
+		{"MGR_CMD_LIST", int(MGR_CMD_LIST), int(C.MGR_CMD_LIST)},
+		{"MGR_CMD_PRINT", int(MGR_CMD_PRINT), int(C.MGR_CMD_PRINT)},
+		{"MGR_CMD_ACTIVE", int(MGR_CMD_ACTIVE), int(C.MGR_CMD_ACTIVE)},
+		{"MGR_OBJ_NONE", int(MGR_OBJ_NONE), int(C.MGR_OBJ_NONE)},
+		{"MGR_OBJ_SERVER", int(MGR_OBJ_SERVER), int(C.MGR_OBJ_SERVER)},
+		{"MGR_OBJ_QUEUE", int(MGR_OBJ_QUEUE), int(C.MGR_OBJ_QUEUE)},
+		{"MGR_OBJ_JOB", int(MGR_OBJ_JOB), int(C.MGR_OBJ_JOB)},
+		{"MGR_OBJ_NODE", int(MGR_OBJ_NODE), int(C.MGR_OBJ_NODE)},
+		{"ATTR_a", string(ATTR_a), C.ATTR_a},
+		{"ATTR_c", string(ATTR_c), C.ATTR_c},
+		{"ATTR_e", string(ATTR_e), C.ATTR_e},
+		{"ATTR_f", string(ATTR_f), C.ATTR_f},
+		{"ATTR_g", string(ATTR_g), C.ATTR_g},
+		{"ATTR_h", string(ATTR_h), C.ATTR_h},
+		{"ATTR_j", string(ATTR_j), C.ATTR_j},
+		{"ATTR_k", string(ATTR_k), C.ATTR_k},
+		{"ATTR_l", string(ATTR_l), C.ATTR_l},
+		{"ATTR_m", string(ATTR_m), C.ATTR_m},
+		{"ATTR_o", string(ATTR_o), C.ATTR_o},
+		{"ATTR_p", string(ATTR_p), C.ATTR_p},
+		{"ATTR_q", string(ATTR_q), C.ATTR_q},
+		{"ATTR_r", string(ATTR_r), C.ATTR_r},
+		{"ATTR_t", string(ATTR_t), C.ATTR_t},
+		{"ATTR_array_id", string(ATTR_array_id), C.ATTR_array_id},
+		{"ATTR_u", string(ATTR_u), C.ATTR_u},
+		{"ATTR_v", string(ATTR_v), C.ATTR_v},
+		{"ATTR_A", string(ATTR_A), C.ATTR_A},
+		{"ATTR_args", string(ATTR_args), C.ATTR_args},
+		{"ATTR_M", string(ATTR_M), C.ATTR_M},
+		{"ATTR_N", string(ATTR_N), C.ATTR_N},
+		{"ATTR_S", string(ATTR_S), C.ATTR_S},
+		{"ATTR_depend", string(ATTR_depend), C.ATTR_depend},
+		{"ATTR_inter", string(ATTR_inter), C.ATTR_inter},
+		{"ATTR_stagein", string(ATTR_stagein), C.ATTR_stagein},
+		{"ATTR_stageout", string(ATTR_stageout), C.ATTR_stageout},
+		{"ATTR_jobtype", string(ATTR_jobtype), C.ATTR_jobtype},
+		{"ATTR_submit_host", string(ATTR_submit_host), C.ATTR_submit_host},
+		{"ATTR_init_work_dir", string(ATTR_init_work_dir), C.ATTR_init_work_dir},
+		{"ATTR_ctime", string(ATTR_ctime), C.ATTR_ctime},
+		{"ATTR_exechost", string(ATTR_exechost), C.ATTR_exechost},
+		{"ATTR_execport", string(ATTR_execport), C.ATTR_execport},
+		{"ATTR_mtime", string(ATTR_mtime), C.ATTR_mtime},
+		{"ATTR_qtime", string(ATTR_qtime), C.ATTR_qtime},
+		{"ATTR_session", string(ATTR_session), C.ATTR_session},
+		{"ATTR_euser", string(ATTR_euser), C.ATTR_euser},
+		{"ATTR_egroup", string(ATTR_egroup), C.ATTR_egroup},
+		{"ATTR_hashname", string(ATTR_hashname), C.ATTR_hashname},
+		{"ATTR_hopcount", string(ATTR_hopcount), C.ATTR_hopcount},
+		{"ATTR_security", string(ATTR_security), C.ATTR_security},
+		{"ATTR_sched_hint", string(ATTR_sched_hint), C.ATTR_sched_hint},
+		{"ATTR_substate", string(ATTR_substate), C.ATTR_substate},
+		{"ATTR_name", string(ATTR_name), C.ATTR_name},
+		{"ATTR_owner", string(ATTR_owner), C.ATTR_owner},
+		{"ATTR_used", string(ATTR_used), C.ATTR_used},
+		{"ATTR_state", string(ATTR_state), C.ATTR_state},
+		{"ATTR_queue", string(ATTR_queue), C.ATTR_queue},
+		{"ATTR_server", string(ATTR_server), C.ATTR_server},
+		{"ATTR_maxrun", string(ATTR_maxrun), C.ATTR_maxrun},
+		{"ATTR_maxreport", string(ATTR_maxreport), C.ATTR_maxreport},
+		{"ATTR_total", string(ATTR_total), C.ATTR_total},
+		{"ATTR_comment", string(ATTR_comment), C.ATTR_comment},
+		{"ATTR_cookie", string(ATTR_cookie), C.ATTR_cookie},
+		{"ATTR_qrank", string(ATTR_qrank), C.ATTR_qrank},
+		{"ATTR_altid", string(ATTR_altid), C.ATTR_altid},
+		{"ATTR_etime", string(ATTR_etime), C.ATTR_etime},
+		{"ATTR_exitstat", string(ATTR_exitstat), C.ATTR_exitstat},
+		{"ATTR_forwardx11", string(ATTR_forwardx11), C.ATTR_forwardx11},
+		{"ATTR_submit_args", string(ATTR_submit_args), C.ATTR_submit_args},
+		{"ATTR_tokens", string(ATTR_tokens), C.ATTR_tokens},
+		{"ATTR_netcounter", string(ATTR_netcounter), C.ATTR_netcounter},
+		{"ATTR_umask", string(ATTR_umask), C.ATTR_umask},
+		{"ATTR_start_time", string(ATTR_start_time), C.ATTR_start_time},
+		{"ATTR_start_count", string(ATTR_start_count), C.ATTR_start_count},
+		{"ATTR_checkpoint_dir", string(ATTR_checkpoint_dir), C.ATTR_checkpoint_dir},
+		{"ATTR_checkpoint_name", string(ATTR_checkpoint_name), C.ATTR_checkpoint_name},
+		{"ATTR_checkpoint_time", string(ATTR_checkpoint_time), C.ATTR_checkpoint_time},
+		{"ATTR_checkpoint_restart_status", string(ATTR_checkpoint_restart_status), C.ATTR_checkpoint_restart_status},
+		{"ATTR_restart_name", string(ATTR_restart_name), C.ATTR_restart_name},
+		{"ATTR_comp_time", string(ATTR_comp_time), C.ATTR_comp_time},
+		{"ATTR_reported", string(ATTR_reported), C.ATTR_reported},
+		{"ATTR_intcmd", string(ATTR_intcmd), C.ATTR_intcmd},
+		{"ATTR_P", string(ATTR_P), C.ATTR_P},
+		{"ATTR_node_exclusive", string(ATTR_node_exclusive), C.ATTR_node_exclusive},
+		{"ATTR_exec_gpus", string(ATTR_exec_gpus), C.ATTR_exec_gpus},
+		{"ATTR_J", string(ATTR_J), C.ATTR_J},
+		{"SET", int(SET), int(C.SET)},
+		{"UNSET", int(UNSET), int(C.UNSET)},
+		{"INCR", int(INCR), int(C.INCR)},
+		{"DECR", int(DECR), int(C.DECR)},
+		{"EQ", int(EQ), int(C.EQ)},
+		{"NE", int(NE), int(C.NE)},
+		{"GE", int(GE), int(C.GE)},
+		{"GT", int(GT), int(C.GT)},
+		{"LE", int(LE), int(C.LE)},
+		{"LT", int(LT), int(C.LT)},
+		{"DFLT", int(DFLT), int(C.DFLT)},
+		{"MERGE", int(MERGE), int(C.MERGE)},
+		{"INCR_OLD", int(INCR_OLD), int(C.INCR_OLD)},
+	} {
+		if c.got != c.want {
+			bad = append(bad, c.name)
+		}
+	}
+	return bad
 }
 
-func attrib2attribl(attribs []Attrib) *C.struct_attrl {
-	// Empty array returns null pointer
-	if len(attribs) == 0 {
-		return nil
+// getLastError builds a *PBSError for the failed operation op on id from
+// pbs_errno. The server's own message is included for valid handles.
+func getLastError(handle int, op string, id string) error {
+	errno := C.pbs_errno
+	err := &PBSError{
+		Op:    op,
+		ID:    id,
+		Errno: int(errno),
+		Msg:   C.GoString(C.pbs_strerror(errno)),
 	}
-
-	first := &C.struct_attrl{
-		value:    C.CString(attribs[0].Value),
-		resource: C.CString(attribs[0].Resource),
-		name:     C.CString(attribs[0].Name),
-		op:       uint32(attribs[0].Op),
+	if handle >= 0 {
+		err.ServerMsg = C.GoString(C.pbs_geterrmsg(C.int(handle)))
 	}
-	tail := first
-
-	for _, attr := range attribs[1:len(attribs)] {
-		tail.next = &C.struct_attrl{
-			value:    C.CString(attr.Value),
-			resource: C.CString(attr.Resource),
-			name:     C.CString(attr.Name),
-			op:       uint32(attribs[0].Op),
+	return err
+}
+
+// call makes a call into libtorque with f, which reports whether the call
+// failed, through the executor set by SetCallMode. The error for a failure
+// is read before any other call can be made.
+func call(handle int, op string, id string, f func() bool) error {
+	var err error
+	libtorque.do(func() {
+		if f() {
+			err = getLastError(handle, op, id)
 		}
-	}
+	})
+	return err
+}
 
+// attrib2attribl converts attribs to a C attrl list, which is allocated
+// with malloc so that it can be passed to libtorque, and must be freed with
+// freeattribl
+func attrib2attribl(attribs []Attrib) *C.struct_attrl {
+	var first, tail *C.struct_attrl
+	for _, attr := range attribs {
+		p := (*C.struct_attrl)(C.calloc(1, C.sizeof_struct_attrl))
+		p.name = C.CString(attr.Name)
+		p.resource = C.CString(attr.Resource)
+		p.value = C.CString(attr.Value)
+		p.op = uint32(attr.Op)
+
+		if first == nil {
+			first = p
+		} else {
+			tail.next = p
+		}
+		tail = p
+	}
 	return first
 }
 
 func freeattribl(attrl *C.struct_attrl) {
-	for p := attrl; p != nil; p = p.next {
+	for p := attrl; p != nil; {
+		next := p.next
 		C.free(unsafe.Pointer(p.name))
 		C.free(unsafe.Pointer(p.value))
 		C.free(unsafe.Pointer(p.resource))
+		C.free(unsafe.Pointer(p))
+		p = next
 	}
 }
 
-func get_pbs_batch_status(batch_status *_Ctype_struct_batch_status) (batch []BatchStatus) {
-	for batch_status != nil {
-		temp := []Attrib{}
-		for attr := batch_status.attribs; attr != nil; attr = attr.next {
-			temp = append(temp, Attrib{
-				Name:     C.GoString(attr.name),
-				Resource: C.GoString(attr.resource),
-				Value:    C.GoString(attr.value),
-			})
-		}
+// attribl2attrib converts a C attrl list to attributes
+func attribl2attrib(attrl *C.struct_attrl) []Attrib {
+	attribs := []Attrib{}
+	for attr := attrl; attr != nil; attr = attr.next {
+		attribs = append(attribs, Attrib{
+			Name:     C.GoString(attr.name),
+			Resource: C.GoString(attr.resource),
+			Value:    C.GoString(attr.value),
+			Op:       Operator(attr.op),
+		})
+	}
+	return attribs
+}
 
+func get_pbs_batch_status(batch_status *C.struct_batch_status) (batch []BatchStatus) {
+	for batch_status != nil {
 		batch = append(batch, BatchStatus{
 			Name:       C.GoString(batch_status.name),
 			Text:       C.GoString(batch_status.text),
-			Attributes: temp,
+			Attributes: attribl2attrib(batch_status.attribs),
 		})
 
 		batch_status = batch_status.next
@@ -251,21 +245,17 @@ func get_pbs_batch_status(batch_status *_Ctype_struct_batch_status) (batch []Bat
 	return batch
 }
 
-func sptr(p uintptr) *C.char {
-	return *(**C.char)(unsafe.Pointer(p))
-}
-
 func cstrings(x **C.char) []string {
 	var s []string
-	for p := uintptr(unsafe.Pointer(x)); sptr(p) != nil; p += unsafe.Sizeof(uintptr(0)) {
-		s = append(s, C.GoString(sptr(p)))
+	for p := x; *p != nil; p = (**C.char)(unsafe.Add(unsafe.Pointer(p), unsafe.Sizeof(p))) {
+		s = append(s, C.GoString(*p))
 	}
 	return s
 }
 
 func freeCstrings(x **C.char) {
-	for p := uintptr(unsafe.Pointer(x)); sptr(p) != nil; p += unsafe.Sizeof(uintptr(0)) {
-		C.free(unsafe.Pointer(sptr(p)))
+	for p := x; *p != nil; p = (**C.char)(unsafe.Add(unsafe.Pointer(p), unsafe.Sizeof(p))) {
+		C.free(unsafe.Pointer(*p))
 	}
 }
 
@@ -279,12 +269,9 @@ func Pbs_alterjob(handle int, id string, attribs []Attrib, extend string) error
 	a := attrib2attribl(attribs)
 	defer freeattribl(a)
 
-	ret := C.pbs_alterjob(C.int(handle), s, a, e)
-	if ret != 0 {
-		return errors.New(Pbs_strerror(int(C.pbs_errno)))
-	}
-
-	return nil
+	return call(handle, "pbs_alterjob", id, func() bool {
+		return C.pbs_alterjob(C.int(handle), s, a, e) != 0
+	})
 }
 
 func Pbs_checkpointjob(handle int, id string, extend string) error {
@@ -294,12 +281,9 @@ func Pbs_checkpointjob(handle int, id string, extend string) error {
 	e := C.CString(extend)
 	defer C.free(unsafe.Pointer(e))
 
-	ret := C.pbs_checkpointjob(C.int(handle), s, e)
-	if ret != 0 {
-		return errors.New(Pbs_strerror(int(C.pbs_errno)))
-	}
-
-	return nil
+	return call(handle, "pbs_checkpointjob", id, func() bool {
+		return C.pbs_checkpointjob(C.int(handle), s, e) != 0
+	})
 }
 
 // Pbs_connect makes a connection to server, or if server is an empty string, the default server. The returned handle is used by subsequent calls to the functions in this package to identify the server.
@@ -307,9 +291,13 @@ func Pbs_connect(server string) (int, error) {
 	str := C.CString(server)
 	defer C.free(unsafe.Pointer(str))
 
-	handle := C.pbs_connect(str)
-	if handle < 0 {
-		return 0, errors.New(Pbs_strerror(int(C.pbs_errno)))
+	var handle C.int
+	err := call(-1, "pbs_connect", server, func() bool {
+		handle = C.pbs_connect(str)
+		return handle < 0
+	})
+	if err != nil {
+		return 0, err
 	}
 
 	return int(handle), nil
@@ -318,7 +306,9 @@ func Pbs_connect(server string) (int, error) {
 // Pbs_default reports the default torque server
 func Pbs_default() string {
 	// char* from pbs_default is statically allocated, so can't be freed
-	return C.GoString(C.pbs_default())
+	var s string
+	libtorque.do(func() { s = C.GoString(C.pbs_default()) })
+	return s
 }
 
 // Pbs_deljob deletes a job on the server
@@ -329,36 +319,36 @@ func Pbs_deljob(handle int, id string, extend string) error {
 	s := C.CString(id)
 	defer C.free(unsafe.Pointer(s))
 
-	ret := C.pbs_deljob(C.int(handle), s, e)
-	if ret != 0 {
-		return errors.New(Pbs_strerror(int(C.pbs_errno)))
-	}
-
-	return nil
+	return call(handle, "pbs_deljob", id, func() bool {
+		return C.pbs_deljob(C.int(handle), s, e) != 0
+	})
 }
 
 func Pbs_disconnect(handle int) error {
-	ret := C.pbs_disconnect(C.int(handle))
-	if ret != 0 {
-		return errors.New(Pbs_strerror(int(C.pbs_errno)))
-	}
-	return nil
+	return call(-1, "pbs_disconnect", "", func() bool {
+		return C.pbs_disconnect(C.int(handle)) != 0
+	})
 }
 
 func Pbs_fbserver() string {
 	// char* from pbs_fbserver is statically allocated, so can't be freed
-	return C.GoString(C.pbs_fbserver())
+	var s string
+	libtorque.do(func() { s = C.GoString(C.pbs_fbserver()) })
+	return s
 }
 
 func Pbs_get_server_list() string {
 	// char* from pbs_get_server_list is statically allocated, so can't be freed
-	return C.GoString(C.pbs_get_server_list())
+	var s string
+	libtorque.do(func() { s = C.GoString(C.pbs_get_server_list()) })
+	return s
 }
 
 func Pbs_geterrmsg(handle int) string {
-	s := C.pbs_geterrmsg(C.int(handle))
 	// char* from pbs_geterrmsg is statically allocated, so can't be freed
-	return C.GoString(s)
+	var s string
+	libtorque.do(func() { s = C.GoString(C.pbs_geterrmsg(C.int(handle))) })
+	return s
 }
 
 func Pbs_gpumode(handle int, mom_node string, gpu_id string, gpu_mode int) error {
@@ -368,11 +358,9 @@ func Pbs_gpumode(handle int, mom_node string, gpu_id string, gpu_mode int) error
 	g := C.CString(gpu_id)
 	defer C.free(unsafe.Pointer(g))
 
-	ret := C.pbs_gpumode(C.int(handle), m, g, C.int(gpu_mode))
-	if ret != 0 {
-		return getLastError()
-	}
-	return nil
+	return call(handle, "pbs_gpumode", mom_node, func() bool {
+		return C.pbs_gpumode(C.int(handle), m, g, C.int(gpu_mode)) != 0
+	})
 }
 
 /*
@@ -383,7 +371,7 @@ func Pbs_gpureset (handle int, mom_node string, gpu_id int, ecc_perm int, ecc_vo
 
     ret := C.pbs_gpureset(C.int(handle), m, C.int(gpu_id), C.int(ecc_perm), C.int(ecc_vol))
     if ret != 0 {
-		return getLastError()
+		return getLastError(handle, "pbs_gpureset", mom_node)
     }
     return nil
 }
@@ -399,21 +387,22 @@ func Pbs_holdjob(handle int, id string, holdType Hold, extend string) error {
 	ht := C.CString(string(holdType))
 	defer C.free(unsafe.Pointer(ht))
 
-	ret := C.pbs_holdjob(C.int(handle), s, ht, e)
-	if ret != 0 {
-		return errors.New(Pbs_strerror(int(C.pbs_errno)))
-	}
-
-	return nil
+	return call(handle, "pbs_holdjob", id, func() bool {
+		return C.pbs_holdjob(C.int(handle), s, ht, e) != 0
+	})
 }
 
 func Pbs_locjob(handle int, id string) (string, error) {
 	s := C.CString(id)
 	defer C.free(unsafe.Pointer(s))
 
-	ret := C.pbs_locjob(C.int(handle), s, nil)
-	if ret == nil {
-		return "", errors.New(Pbs_strerror(int(C.pbs_errno)))
+	var ret *C.char
+	err := call(handle, "pbs_locjob", id, func() bool {
+		ret = C.pbs_locjob(C.int(handle), s, nil)
+		return ret == nil
+	})
+	if err != nil {
+		return "", err
 	}
 	defer C.free(unsafe.Pointer(ret))
 
@@ -430,12 +419,9 @@ func Pbs_manager(handle int, command Command, obj_type ObjectType, obj_name stri
 	a := attrib2attribl(attrib)
 	defer freeattribl(a)
 
-	ret := C.pbs_manager(C.int(handle), C.int(command), C.int(obj_type), name, (*C.struct_attropl)(unsafe.Pointer(a)), e)
-	if ret != 0 {
-		return errors.New(Pbs_strerror(int(C.pbs_errno)))
-	}
-
-	return nil
+	return call(handle, "pbs_manager", obj_name, func() bool {
+		return C.pbs_manager(C.int(handle), C.int(command), C.int(obj_type), name, (*C.struct_attropl)(unsafe.Pointer(a)), e) != 0
+	})
 }
 
 func Pbs_selstat(handle int, attribs []Attrib, extend string) ([]BatchStatus, error) {
@@ -445,11 +431,15 @@ func Pbs_selstat(handle int, attribs []Attrib, extend string) ([]BatchStatus, er
 	e := C.CString(extend)
 	defer C.free(unsafe.Pointer(e))
 
-	batch_status := C.pbs_selstat(C.int(handle), (*C.struct_attropl)(unsafe.Pointer(a)), e)
+	var batch_status *C.struct_batch_status
+	err := call(handle, "pbs_selstat", "", func() bool {
+		batch_status = C.pbs_selstat(C.int(handle), (*C.struct_attropl)(unsafe.Pointer(a)), e)
+		return batch_status == nil
+	})
 
 	// FIXME: nil also indicates no jobs matched selection criteria...
-	if batch_status == nil {
-		return nil, errors.New(Pbs_strerror(int(C.pbs_errno)))
+	if err != nil {
+		return nil, err
 	}
 	defer C.pbs_statfree(batch_status)
 	batch := get_pbs_batch_status(batch_status)
@@ -467,12 +457,9 @@ func Pbs_movejob(handle int, id string, destination string, extend string) error
 	e := C.CString(extend)
 	defer C.free(unsafe.Pointer(e))
 
-	ret := C.pbs_movejob(C.int(handle), i, d, e)
-	if ret != 0 {
-		return errors.New(Pbs_strerror(int(C.pbs_errno)))
-	}
-
-	return nil
+	return call(handle, "pbs_movejob", id, func() bool {
+		return C.pbs_movejob(C.int(handle), i, d, e) != 0
+	})
 }
 
 func Pbs_msgjob(handle int, id string, file MessageStream, message string, extend string) error {
@@ -485,11 +472,9 @@ func Pbs_msgjob(handle int, id string, file MessageStream, message string, exten
 	m := C.CString(message)
 	defer C.free(unsafe.Pointer(m))
 
-	ret := C.pbs_msgjob(C.int(handle), s, C.int(file), m, e)
-	if ret != 0 {
-		return errors.New(Pbs_strerror(int(C.pbs_errno)))
-	}
-	return nil
+	return call(handle, "pbs_msgjob", id, func() bool {
+		return C.pbs_msgjob(C.int(handle), s, C.int(file), m, e) != 0
+	})
 }
 
 func Pbs_orderjob(handle int, job_id1 string, job_id2, extend string) error {
@@ -502,11 +487,9 @@ func Pbs_orderjob(handle int, job_id1 string, job_id2, extend string) error {
 	e := C.CString(extend)
 	defer C.free(unsafe.Pointer(e))
 
-	ret := C.pbs_orderjob(C.int(handle), j1, j2, e)
-	if ret != 0 {
-		return errors.New(Pbs_strerror(int(C.pbs_errno)))
-	}
-	return nil
+	return call(handle, "pbs_orderjob", job_id1, func() bool {
+		return C.pbs_orderjob(C.int(handle), j1, j2, e) != 0
+	})
 }
 
 func cstringArray(strings []string) **C.char {
@@ -523,9 +506,11 @@ func Pbs_rescquery(handle int, resources []string) (int, int, int, int, error) {
 	rl := cstringArray(resources)
 	defer C.freeCstringsN(rl, C.uint(len(resources)))
 
-	ret := C.pbs_rescquery(C.int(handle), rl, C.int(len(resources)), &avail, &alloc, &reserv, &down)
-	if ret != 0 {
-		return 0, 0, 0, 0, errors.New(Pbs_strerror(int(C.pbs_errno)))
+	err := call(handle, "pbs_rescquery", "", func() bool {
+		return C.pbs_rescquery(C.int(handle), rl, C.int(len(resources)), &avail, &alloc, &reserv, &down) != 0
+	})
+	if err != nil {
+		return 0, 0, 0, 0, err
 	}
 
 	return int(avail), int(alloc), int(reserv), int(down), nil
@@ -538,38 +523,41 @@ func Pbs_rerunjob(handle int, id string, extend string) error {
 	e := C.CString(extend)
 	defer C.free(unsafe.Pointer(e))
 
-	ret := C.pbs_rerunjob(C.int(handle), s, e)
-
-	if ret != 0 {
-		return errors.New(Pbs_strerror(int(C.pbs_errno)))
-	}
-	return nil
+	return call(handle, "pbs_rerunjob", id, func() bool {
+		return C.pbs_rerunjob(C.int(handle), s, e) != 0
+	})
 }
 
 func Avail(handle int, resc string) string {
 	r := C.CString(resc)
 	defer C.free(unsafe.Pointer(r))
 
-	c := C.avail(C.int(handle), r)
-	//defer C.free(unsafe.Pointer(c))
+	var s string
+	libtorque.do(func() {
+		c := C.avail(C.int(handle), r)
+		//defer C.free(unsafe.Pointer(c))
+		s = C.GoString(c)
+	})
 
-	return C.GoString(c)
+	return s
 }
 
 func Totpool(handle int, update int) (int, error) {
-	ret := int(C.totpool(C.int(handle), C.int(update)))
-	if ret < 0 {
-		return ret, errors.New(Pbs_strerror(int(C.pbs_errno)))
-	}
-	return ret, nil
+	var ret int
+	err := call(handle, "totpool", "", func() bool {
+		ret = int(C.totpool(C.int(handle), C.int(update)))
+		return ret < 0
+	})
+	return ret, err
 }
 
 func Usepool(handle int, update int) (int, error) {
-	ret := int(C.usepool(C.int(handle), C.int(update)))
-	if ret < 0 {
-		return ret, errors.New(Pbs_strerror(int(C.pbs_errno)))
-	}
-	return ret, nil
+	var ret int
+	err := call(handle, "usepool", "", func() bool {
+		ret = int(C.usepool(C.int(handle), C.int(update)))
+		return ret < 0
+	})
+	return ret, err
 }
 
 func Pbs_rlsjob(handle int, id string, holdType Hold, extend string) error {
@@ -582,12 +570,9 @@ func Pbs_rlsjob(handle int, id string, holdType Hold, extend string) error {
 	ht := C.CString(string(holdType))
 	defer C.free(unsafe.Pointer(ht))
 
-	ret := C.pbs_rlsjob(C.int(handle), s, ht, e)
-	if ret != 0 {
-		return errors.New(Pbs_strerror(int(C.pbs_errno)))
-	}
-
-	return nil
+	return call(handle, "pbs_rlsjob", id, func() bool {
+		return C.pbs_rlsjob(C.int(handle), s, ht, e) != 0
+	})
 }
 
 func Pbs_runjob(handle int, id string, location string, extend string) error {
@@ -600,11 +585,9 @@ func Pbs_runjob(handle int, id string, location string, extend string) error {
 	e := C.CString(extend)
 	defer C.free(unsafe.Pointer(e))
 
-	ret := C.pbs_runjob(C.int(handle), i, l, e)
-	if ret != 0 {
-		return errors.New(Pbs_strerror(int(C.pbs_errno)))
-	}
-	return nil
+	return call(handle, "pbs_runjob", id, func() bool {
+		return C.pbs_runjob(C.int(handle), i, l, e) != 0
+	})
 }
 
 // Pbs_selectjob returns a list of jobs
@@ -620,9 +603,13 @@ func Pbs_selectjob(handle int, attrib []Attrib, extend string) ([]string, error)
 	a := attrib2attribl(attrib)
 	defer freeattribl(a)
 
-	p := C.pbs_selectjob(C.int(handle), (*C.struct_attropl)(unsafe.Pointer(a)), e)
-	if p == nil {
-		return nil, errors.New(Pbs_strerror(int(C.pbs_errno)))
+	var p **C.char
+	err := call(handle, "pbs_selectjob", "", func() bool {
+		p = C.pbs_selectjob(C.int(handle), (*C.struct_attropl)(unsafe.Pointer(a)), e)
+		return p == nil
+	})
+	if err != nil {
+		return nil, err
 	}
 	defer C.free(unsafe.Pointer(p))
 
@@ -640,11 +627,9 @@ func Pbs_sigjob(handle int, id string, signal string, extend string) error {
 	e := C.CString(extend)
 	defer C.free(unsafe.Pointer(e))
 
-	ret := C.pbs_sigjob(C.int(handle), i, s, e)
-	if ret != 0 {
-		return errors.New(Pbs_strerror(int(C.pbs_errno)))
-	}
-	return nil
+	return call(handle, "pbs_sigjob", id, func() bool {
+		return C.pbs_sigjob(C.int(handle), i, s, e) != 0
+	})
 }
 
 /*
@@ -661,7 +646,7 @@ func Pbs_stagein(handle int, id string, location string, extend string) error {
 
     ret := C.pbs_stagein(C.int(handle), i, l, e)
 	if ret != 0 {
-		return errors.New(Pbs_strerror(int(C.pbs_errno)))
+		return getLastError(handle, "pbs_stagein", id)
 	}
 	return nil
 }
@@ -677,10 +662,13 @@ func Pbs_statjob(handle int, id string, attribs []Attrib, extend string) ([]Batc
 	a := attrib2attribl(attribs)
 	defer freeattribl(a)
 
-	batch_status := C.pbs_statjob(C.int(handle), i, a, e)
-
-	if batch_status == nil {
-		return nil, errors.New(Pbs_strerror(int(C.pbs_errno)))
+	var batch_status *C.struct_batch_status
+	err := call(handle, "pbs_statjob", id, func() bool {
+		batch_status = C.pbs_statjob(C.int(handle), i, a, e)
+		return batch_status == nil
+	})
+	if err != nil {
+		return nil, err
 	}
 	defer C.pbs_statfree(batch_status)
 
@@ -699,10 +687,13 @@ func Pbs_statnode(handle int, id string, attribs []Attrib, extend string) ([]Bat
 	e := C.CString(extend)
 	defer C.free(unsafe.Pointer(e))
 
-	batch_status := C.pbs_statnode(C.int(handle), i, a, e)
-
-	if batch_status == nil {
-		return nil, errors.New(Pbs_strerror(int(C.pbs_errno)))
+	var batch_status *C.struct_batch_status
+	err := call(handle, "pbs_statnode", id, func() bool {
+		batch_status = C.pbs_statnode(C.int(handle), i, a, e)
+		return batch_status == nil
+	})
+	if err != nil {
+		return nil, err
 	}
 	defer C.pbs_statfree(batch_status)
 
@@ -721,10 +712,13 @@ func Pbs_statque(handle int, id string, attribs []Attrib, extend string) ([]Batc
 	e := C.CString(extend)
 	defer C.free(unsafe.Pointer(e))
 
-	batch_status := C.pbs_statque(C.int(handle), i, a, e)
-
-	if batch_status == nil {
-		return nil, errors.New(Pbs_strerror(int(C.pbs_errno)))
+	var batch_status *C.struct_batch_status
+	err := call(handle, "pbs_statque", id, func() bool {
+		batch_status = C.pbs_statque(C.int(handle), i, a, e)
+		return batch_status == nil
+	})
+	if err != nil {
+		return nil, err
 	}
 	defer C.pbs_statfree(batch_status)
 
@@ -740,10 +734,13 @@ func Pbs_statserver(handle int, attribs []Attrib, extend string) ([]BatchStatus,
 	e := C.CString(extend)
 	defer C.free(unsafe.Pointer(e))
 
-	batch_status := C.pbs_statserver(C.int(handle), a, e)
-
-	if batch_status == nil {
-		return nil, errors.New(Pbs_strerror(int(C.pbs_errno)))
+	var batch_status *C.struct_batch_status
+	err := call(handle, "pbs_statserver", "", func() bool {
+		batch_status = C.pbs_statserver(C.int(handle), a, e)
+		return batch_status == nil
+	})
+	if err != nil {
+		return nil, err
 	}
 	defer C.pbs_statfree(batch_status)
 
@@ -754,7 +751,9 @@ func Pbs_statserver(handle int, attribs []Attrib, extend string) ([]BatchStatus,
 
 func Pbs_strerror(errno int) string {
 	// char* from pbs_strerror is statically allocated, so can't be freed
-	return C.GoString(C.pbs_strerror(C.int(errno)))
+	var s string
+	libtorque.do(func() { s = C.GoString(C.pbs_strerror(C.int(errno))) })
+	return s
 }
 
 func Pbs_submit(handle int, attribs []Attrib, script string, destination string, extend string) (string, error) {
@@ -770,9 +769,13 @@ func Pbs_submit(handle int, attribs []Attrib, script string, destination string,
 	e := C.CString(extend)
 	defer C.free(unsafe.Pointer(e))
 
-	jobid := C.pbs_submit(C.int(handle), (*C.struct_attropl)(unsafe.Pointer(a)), s, d, e)
-	if jobid == nil {
-		return "", errors.New(Pbs_strerror(int(C.pbs_errno)))
+	var jobid *C.char
+	err := call(handle, "pbs_submit", script, func() bool {
+		jobid = C.pbs_submit(C.int(handle), (*C.struct_attropl)(unsafe.Pointer(a)), s, d, e)
+		return jobid == nil
+	})
+	if err != nil {
+		return "", err
 	}
 	defer C.free(unsafe.Pointer(jobid))
 
@@ -783,9 +786,7 @@ func Pbs_terminate(handle int, manner Manner, extend string) error {
 	e := C.CString(extend)
 	defer C.free(unsafe.Pointer(e))
 
-	ret := C.pbs_terminate(C.int(handle), C.int(int(manner)), e)
-	if ret != 0 {
-		return errors.New(Pbs_strerror(int(C.pbs_errno)))
-	}
-	return nil
+	return call(handle, "pbs_terminate", "", func() bool {
+		return C.pbs_terminate(C.int(handle), C.int(int(manner)), e) != 0
+	})
 }
@@ -0,0 +1,205 @@
+//go:build !cgo || nolibtorque
+
+package pbs
+
+import (
+	"errors"
+	"sync"
+)
+
+// DefaultBackend is the Backend used by Connect
+var DefaultBackend Backend = DIS{}
+
+// Without libtorque the Pbs_* functions for the operations in Client are
+// implemented with the DIS Backend, with handles standing for connections
+// as they do in libtorque.
+
+type disHandle struct {
+	client Client
+	errmsg string
+}
+
+var disHandles = struct {
+	sync.Mutex
+	next    int
+	handles map[int]*disHandle
+}{handles: map[int]*disHandle{}}
+
+// withHandle calls f with the Client for handle, keeping the server's
+// message from any error for Pbs_geterrmsg
+func withHandle(handle int, op string, f func(Client) error) error {
+	disHandles.Lock()
+	h, ok := disHandles.handles[handle]
+	disHandles.Unlock()
+	if !ok {
+		return &PBSError{Op: op, Errno: PBSE_NOCONNECTS, Msg: errorText[PBSE_NOCONNECTS]}
+	}
+
+	err := f(h.client)
+
+	var pe *PBSError
+	disHandles.Lock()
+	h.errmsg = ""
+	if errors.As(err, &pe) {
+		h.errmsg = pe.ServerMsg
+	}
+	disHandles.Unlock()
+	return err
+}
+
+// Pbs_connect makes a connection to server, or if server is an empty string, the default server. The returned handle is used by subsequent calls to the functions in this package to identify the server.
+func Pbs_connect(server string) (int, error) {
+	client, err := DIS{}.Connect(server)
+	if err != nil {
+		return 0, err
+	}
+
+	disHandles.Lock()
+	defer disHandles.Unlock()
+	disHandles.next++
+	disHandles.handles[disHandles.next] = &disHandle{client: client}
+	return disHandles.next, nil
+}
+
+// Pbs_default reports the default torque server
+func Pbs_default() string {
+	return disDefaultServer()
+}
+
+// Pbs_get_server_list returns the comma separated list of servers, from the
+// same places as Pbs_default
+func Pbs_get_server_list() string {
+	return disServerList()
+}
+
+// Pbs_fbserver returns the fallback server, the second in the server list
+func Pbs_fbserver() string {
+	if servers := ParseServerList(disServerList()); len(servers) > 1 {
+		return servers[1]
+	}
+	return ""
+}
+
+func Pbs_disconnect(handle int) error {
+	err := withHandle(handle, "pbs_disconnect", func(c Client) error {
+		return c.Disconnect()
+	})
+
+	disHandles.Lock()
+	delete(disHandles.handles, handle)
+	disHandles.Unlock()
+	return err
+}
+
+func Pbs_geterrmsg(handle int) string {
+	disHandles.Lock()
+	defer disHandles.Unlock()
+	if h, ok := disHandles.handles[handle]; ok {
+		return h.errmsg
+	}
+	return ""
+}
+
+func Pbs_strerror(errno int) string {
+	return errorText[errno]
+}
+
+func Pbs_alterjob(handle int, id string, attribs []Attrib, extend string) error {
+	return withHandle(handle, "pbs_alterjob", func(c Client) error {
+		return c.AlterJob(id, attribs, extend)
+	})
+}
+
+// Pbs_deljob deletes a job on the server
+func Pbs_deljob(handle int, id string, extend string) error {
+	return withHandle(handle, "pbs_deljob", func(c Client) error {
+		return c.DelJob(id, extend)
+	})
+}
+
+func Pbs_holdjob(handle int, id string, holdType Hold, extend string) error {
+	return withHandle(handle, "pbs_holdjob", func(c Client) error {
+		return c.HoldJob(id, holdType, extend)
+	})
+}
+
+func Pbs_manager(handle int, command Command, obj_type ObjectType, obj_name string, attrib []Attrib, extend string) error {
+	return withHandle(handle, "pbs_manager", func(c Client) error {
+		return c.Manager(command, obj_type, obj_name, attrib, extend)
+	})
+}
+
+func Pbs_movejob(handle int, id string, destination string, extend string) error {
+	return withHandle(handle, "pbs_movejob", func(c Client) error {
+		return c.MoveJob(id, destination, extend)
+	})
+}
+
+func Pbs_rlsjob(handle int, id string, holdType Hold, extend string) error {
+	return withHandle(handle, "pbs_rlsjob", func(c Client) error {
+		return c.RlsJob(id, holdType, extend)
+	})
+}
+
+// Pbs_selectjob returns a list of jobs
+func Pbs_selectjob(handle int, attrib []Attrib, extend string) (jobs []string, err error) {
+	err = withHandle(handle, "pbs_selectjob", func(c Client) error {
+		jobs, err = c.SelectJob(attrib, extend)
+		return err
+	})
+	return jobs, err
+}
+
+func Pbs_selstat(handle int, attribs []Attrib, extend string) (batch []BatchStatus, err error) {
+	err = withHandle(handle, "pbs_selstat", func(c Client) error {
+		batch, err = c.SelStat(attribs, extend)
+		return err
+	})
+	return batch, err
+}
+
+func Pbs_sigjob(handle int, id string, signal string, extend string) error {
+	return withHandle(handle, "pbs_sigjob", func(c Client) error {
+		return c.SigJob(id, signal, extend)
+	})
+}
+
+func Pbs_statjob(handle int, id string, attribs []Attrib, extend string) (batch []BatchStatus, err error) {
+	err = withHandle(handle, "pbs_statjob", func(c Client) error {
+		batch, err = c.StatJob(id, attribs, extend)
+		return err
+	})
+	return batch, err
+}
+
+func Pbs_statnode(handle int, id string, attribs []Attrib, extend string) (batch []BatchStatus, err error) {
+	err = withHandle(handle, "pbs_statnode", func(c Client) error {
+		batch, err = c.StatNode(id, attribs, extend)
+		return err
+	})
+	return batch, err
+}
+
+func Pbs_statque(handle int, id string, attribs []Attrib, extend string) (batch []BatchStatus, err error) {
+	err = withHandle(handle, "pbs_statque", func(c Client) error {
+		batch, err = c.StatQue(id, attribs, extend)
+		return err
+	})
+	return batch, err
+}
+
+func Pbs_statserver(handle int, attribs []Attrib, extend string) (batch []BatchStatus, err error) {
+	err = withHandle(handle, "pbs_statserver", func(c Client) error {
+		batch, err = c.StatServer(attribs, extend)
+		return err
+	})
+	return batch, err
+}
+
+func Pbs_submit(handle int, attribs []Attrib, script string, destination string, extend string) (jobid string, err error) {
+	err = withHandle(handle, "pbs_submit", func(c Client) error {
+		jobid, err = c.Submit(attribs, script, destination, extend)
+		return err
+	})
+	return jobid, err
+}
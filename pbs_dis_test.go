@@ -0,0 +1,54 @@
+//go:build !cgo || nolibtorque
+
+package pbs
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDISHandles(t *testing.T) {
+	s := NewFakeServer("fake")
+	client, _ := s.Connect("")
+
+	disHandles.Lock()
+	disHandles.next++
+	handle := disHandles.next
+	disHandles.handles[handle] = &disHandle{client: client}
+	disHandles.Unlock()
+
+	_, err := Pbs_statjob(handle, "1.fake", nil, "")
+	if !errors.Is(err, ErrUnknownJob) {
+		t.Errorf("Pbs_statjob of unknown job returned %v\n", err)
+	}
+	if errmsg := Pbs_geterrmsg(handle); errmsg != "Unknown Job Id 1.fake" {
+		t.Errorf("errmsg is: %s\n", errmsg)
+	}
+
+	if _, err := Pbs_statserver(handle, nil, ""); err != nil {
+		t.Errorf("Couldn't get server statistics: %s\n", err)
+	}
+	if errmsg := Pbs_geterrmsg(handle); errmsg != "" {
+		t.Errorf("errmsg is: %s\n", errmsg)
+	}
+
+	if err := Pbs_disconnect(handle); err != nil {
+		t.Errorf("Disconnect failed: %s\n", err)
+	}
+	if _, err := Pbs_statserver(handle, nil, ""); !errors.Is(err, ErrNoConnects) {
+		t.Errorf("Pbs_statserver after disconnect returned %v\n", err)
+	}
+}
+
+func TestDISServerList(t *testing.T) {
+	t.Setenv("PBS_DEFAULT", "primary,fallback")
+	if s := Pbs_default(); s != "primary" {
+		t.Errorf("Pbs_default returned %q\n", s)
+	}
+	if s := Pbs_fbserver(); s != "fallback" {
+		t.Errorf("Pbs_fbserver returned %q\n", s)
+	}
+	if servers := ServerList(); len(servers) != 2 || servers[1] != "fallback" {
+		t.Errorf("ServerList returned %q\n", servers)
+	}
+}
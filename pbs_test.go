@@ -1,8 +1,11 @@
+//go:build cgo && !nolibtorque
+
 package pbs
 
 import (
 	"os"
 	"os/user"
+	"reflect"
 	"testing"
 )
 
@@ -282,3 +285,54 @@ func TestSelstat(t *testing.T) {
         }
     }
 }
+
+func TestClient(t *testing.T) {
+	var backend Backend = Torque{}
+
+	client, err := backend.Connect(server)
+	if err != nil {
+		t.Fatalf("Connect to %s failed: %s\n", server, err)
+	}
+
+	defer func() {
+		err = client.Disconnect()
+		if err != nil {
+			t.Errorf("Disconnect failed: %s\n", err)
+		}
+	}()
+
+	attribs, err := client.StatServer(nil, "")
+	if err != nil {
+		t.Errorf("Couldn't get server statistics: %s\n", err)
+	}
+
+	for _, server := range attribs {
+		t.Logf("%s (%s)\n", server.Name, server.Text)
+		for _, attr := range server.Attributes {
+			logAttribute(t, attr)
+		}
+	}
+}
+
+func TestHeaders(t *testing.T) {
+	for _, name := range headerMismatches() {
+		t.Errorf("%s doesn't match the TORQUE headers\n", name)
+	}
+}
+
+func TestAttribList(t *testing.T) {
+	attribs := []Attrib{
+		{Name: ATTR_N, Value: "job"},
+		{Name: ATTR_l, Resource: "nodes", Value: "1:ppn=4"},
+		{Name: ATTR_h, Value: "u", Op: UNSET},
+		{Name: ATTR_v, Value: "A=1,B=2", Op: INCR},
+	}
+	a := attrib2attribl(attribs)
+	defer freeattribl(a)
+	if got := attribl2attrib(a); !reflect.DeepEqual(got, attribs) {
+		t.Errorf("attrib list round tripped as %+v\n", got)
+	}
+	if a := attrib2attribl(nil); a != nil {
+		t.Errorf("attrib2attribl of no attributes returned a list\n")
+	}
+}
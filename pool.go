@@ -0,0 +1,255 @@
+package pbs
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// Pool defaults
+const (
+	DefaultMaxConns    = 4
+	DefaultHealthCheck = 30 * time.Second
+)
+
+// Pool keeps connections to servers for reuse, so that a busy program
+// doesn't open a connection for every request. It is safe for concurrent
+// use. The zero value is ready to use.
+type Pool struct {
+	// Backend makes the connections, DefaultBackend if nil
+	Backend Backend
+
+	// MaxConns limits the connections to each server, idle or in use.
+	// Get waits for one to be returned once the limit is reached. Zero
+	// means DefaultMaxConns.
+	MaxConns int
+
+	// HealthCheck is how long a connection can be idle before it is
+	// checked, with a StatServer request, before being reused. Zero means
+	// DefaultHealthCheck, a negative value checks every time.
+	HealthCheck time.Duration
+
+	mu      sync.Mutex
+	servers map[string]*poolServer
+	inUse   map[*Conn]*poolServer
+	closed  bool
+}
+
+// poolServer is the state of the connections to a server
+type poolServer struct {
+	name  string
+	slots chan struct{}
+	idle  []poolConn
+}
+
+type poolConn struct {
+	conn *Conn
+	used time.Time
+}
+
+// server returns the state for server, or an error once p is closed
+func (p *Pool) server(server string) (*poolServer, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return nil, closedError("pbs_connect", server)
+	}
+	if p.servers == nil {
+		p.servers = map[string]*poolServer{}
+		p.inUse = map[*Conn]*poolServer{}
+	}
+	s, ok := p.servers[server]
+	if !ok {
+		max := p.MaxConns
+		if max <= 0 {
+			max = DefaultMaxConns
+		}
+		s = &poolServer{name: server, slots: make(chan struct{}, max)}
+		p.servers[server] = s
+	}
+	return s, nil
+}
+
+// Get returns a connection to server, reusing an idle one if it is
+// healthy. It waits while MaxConns connections to server are in use. The
+// connection must be given back with Put.
+func (p *Pool) Get(ctx context.Context, server string) (*Conn, error) {
+	s, err := p.server(server)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case s.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, contextError("pbs_connect", server, ctx.Err())
+	}
+
+	for {
+		pc, ok := p.popIdle(s)
+		if !ok {
+			break
+		}
+		// A cancelled ctx would fail the health check, and the connection
+		// isn't to blame for that
+		if ctx.Err() == nil && p.healthy(ctx, pc) {
+			return p.borrowed(s, pc.conn)
+		}
+		if ctx.Err() != nil && !pc.conn.isClosed() {
+			p.pushIdle(s, pc)
+			<-s.slots
+			return nil, contextError("pbs_connect", server, ctx.Err())
+		}
+		pc.conn.Close()
+	}
+
+	backend := p.Backend
+	if backend == nil {
+		backend = DefaultBackend
+	}
+	conn, err := ConnectWithContext(ctx, backend, server)
+	if err != nil {
+		<-s.slots
+		return nil, err
+	}
+	return p.borrowed(s, conn)
+}
+
+// pushIdle gives back an idle connection taken by popIdle, or closes it
+// if p has been closed since
+func (p *Pool) pushIdle(s *poolServer, pc poolConn) {
+	p.mu.Lock()
+	closed := p.closed
+	if !closed {
+		s.idle = append(s.idle, pc)
+	}
+	p.mu.Unlock()
+
+	if closed {
+		pc.conn.Close()
+	}
+}
+
+func (p *Pool) popIdle(s *poolServer) (poolConn, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if len(s.idle) == 0 {
+		return poolConn{}, false
+	}
+	pc := s.idle[len(s.idle)-1]
+	s.idle = s.idle[:len(s.idle)-1]
+	return pc, true
+}
+
+// healthy checks an idle connection if it has been idle long enough
+func (p *Pool) healthy(ctx context.Context, pc poolConn) bool {
+	check := p.HealthCheck
+	if check == 0 {
+		check = DefaultHealthCheck
+	}
+	if check > 0 && time.Since(pc.used) < check {
+		return true
+	}
+	_, err := pc.conn.StatServerContext(ctx, []Attrib{{Name: ATTR_total}}, "")
+	return err == nil
+}
+
+// borrowed records that conn is in use, closing it if p has been closed
+func (p *Pool) borrowed(s *poolServer, conn *Conn) (*Conn, error) {
+	p.mu.Lock()
+	closed := p.closed
+	if !closed {
+		p.inUse[conn] = s
+	}
+	p.mu.Unlock()
+
+	if closed {
+		conn.Close()
+		<-s.slots
+		return nil, closedError("pbs_connect", s.name)
+	}
+	return conn, nil
+}
+
+// Put gives back a connection from Get, with the last error from using it.
+// Connections whose errors mean they are broken, see IsConnError, are
+// closed rather than reused, as are connections which have been closed,
+// e.g. by abandoning a request.
+func (p *Pool) Put(conn *Conn, err error) {
+	p.mu.Lock()
+	s, ok := p.inUse[conn]
+	if !ok {
+		p.mu.Unlock()
+		return
+	}
+	delete(p.inUse, conn)
+	reuse := !p.closed && !IsConnError(err) && !conn.isClosed()
+	if reuse {
+		s.idle = append(s.idle, poolConn{conn: conn, used: time.Now()})
+	}
+	p.mu.Unlock()
+
+	// Closing is a request to the server, so it's done without the lock
+	if !reuse {
+		conn.Close()
+	}
+	<-s.slots
+}
+
+// Do calls f with a connection to server. If f fails with a connection
+// error it is called again, once, with a new connection, so it shouldn't
+// do anything which can't be repeated, such as submitting a job, unless
+// that is acceptable.
+func (p *Pool) Do(ctx context.Context, server string, f func(*Conn) error) error {
+	for attempt := 0; ; attempt++ {
+		conn, err := p.Get(ctx, server)
+		if err != nil {
+			return err
+		}
+		err = f(conn)
+		p.Put(conn, err)
+		if attempt > 0 || !IsConnError(err) || ctx.Err() != nil {
+			return err
+		}
+	}
+}
+
+// Close closes the idle connections, and those in use as they are
+// given back. Get then fails with ErrClosed.
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	p.closed = true
+	var idle []poolConn
+	for _, s := range p.servers {
+		idle = append(idle, s.idle...)
+		s.idle = nil
+	}
+	p.mu.Unlock()
+
+	var first error
+	for _, pc := range idle {
+		if err := pc.conn.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// IsConnError reports whether err means that the connection it came from
+// is broken, rather than the request having failed
+func IsConnError(err error) bool {
+	if err == nil {
+		return false
+	}
+	for _, target := range []error{ErrProtocol, ErrDISProtocol, ErrNoServer, ErrServerDown, ErrNoConnects, ErrClosed} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	var ne net.Error
+	return errors.As(err, &ne)
+}
@@ -0,0 +1,190 @@
+package pbs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingBackend counts the connections made with Backend
+type countingBackend struct {
+	Backend
+	connects int32
+}
+
+func (b *countingBackend) Connect(server string) (Client, error) {
+	atomic.AddInt32(&b.connects, 1)
+	return b.Backend.Connect(server)
+}
+
+func (b *countingBackend) count() int {
+	return int(atomic.LoadInt32(&b.connects))
+}
+
+func TestPoolReuse(t *testing.T) {
+	backend := &countingBackend{Backend: NewFakeServer("fake")}
+	pool := &Pool{Backend: backend}
+	defer pool.Close()
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		err := pool.Do(ctx, "", func(conn *Conn) error {
+			_, err := conn.StatServer(nil, "")
+			return err
+		})
+		if err != nil {
+			t.Fatalf("Do failed: %s\n", err)
+		}
+	}
+	if backend.count() != 1 {
+		t.Errorf("Made %d connections for sequential requests\n", backend.count())
+	}
+
+	// A broken connection is replaced
+	conn, err := pool.Get(ctx, "")
+	if err != nil {
+		t.Fatalf("Get failed: %s\n", err)
+	}
+	pool.Put(conn, ErrServerDown)
+	if conn, err = pool.Get(ctx, ""); err != nil {
+		t.Fatalf("Get failed: %s\n", err)
+	}
+	pool.Put(conn, nil)
+	if backend.count() != 2 {
+		t.Errorf("Made %d connections, expected a reconnect\n", backend.count())
+	}
+}
+
+func TestPoolLimit(t *testing.T) {
+	pool := &Pool{Backend: NewFakeServer("fake"), MaxConns: 1}
+	defer pool.Close()
+
+	conn, err := pool.Get(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Get failed: %s\n", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := pool.Get(ctx, ""); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Get beyond MaxConns returned %v\n", err)
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		pool.Put(conn, nil)
+	}()
+	again, err := pool.Get(context.Background(), "")
+	if err != nil || again != conn {
+		t.Errorf("Get after Put returned %p, %v; expected %p\n", again, err, conn)
+	}
+}
+
+func TestPoolHealthCheck(t *testing.T) {
+	backend := &countingBackend{Backend: NewFakeServer("fake")}
+	pool := &Pool{Backend: backend, HealthCheck: -1}
+	defer pool.Close()
+	ctx := context.Background()
+
+	conn, err := pool.Get(ctx, "")
+	if err != nil {
+		t.Fatalf("Get failed: %s\n", err)
+	}
+	pool.Put(conn, nil)
+
+	// Break the connection behind the pool's back
+	conn.client.Disconnect()
+
+	again, err := pool.Get(ctx, "")
+	if err != nil {
+		t.Fatalf("Get failed: %s\n", err)
+	}
+	if again == conn || backend.count() != 2 {
+		t.Errorf("Broken connection was reused\n")
+	}
+	if _, err := again.StatServer(nil, ""); err != nil {
+		t.Errorf("New connection failed: %s\n", err)
+	}
+	pool.Put(again, nil)
+}
+
+func TestPoolCancelledGet(t *testing.T) {
+	backend := &countingBackend{Backend: NewFakeServer("fake")}
+	pool := &Pool{Backend: backend, HealthCheck: -1}
+	defer pool.Close()
+	ctx := context.Background()
+
+	first, _ := pool.Get(ctx, "")
+	second, _ := pool.Get(ctx, "")
+	pool.Put(first, nil)
+	pool.Put(second, nil)
+
+	// A cancelled Get leaves the idle connections for the next. Get only
+	// sometimes gets as far as the idle connections, as it also waits for
+	// ctx, so it's tried a few times.
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	for i := 0; i < 20; i++ {
+		if _, err := pool.Get(cancelled, ""); !errors.Is(err, context.Canceled) {
+			t.Errorf("Get with a cancelled context returned %v\n", err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		conn, err := pool.Get(ctx, "")
+		if err != nil {
+			t.Fatalf("Get failed: %s\n", err)
+		}
+		defer pool.Put(conn, nil)
+	}
+	if backend.count() != 2 {
+		t.Errorf("Made %d connections, expected the idle ones to be reused\n", backend.count())
+	}
+}
+
+func TestPoolDoRetry(t *testing.T) {
+	backend := &countingBackend{Backend: NewFakeServer("fake")}
+	pool := &Pool{Backend: backend}
+	defer pool.Close()
+
+	calls := 0
+	err := pool.Do(context.Background(), "", func(conn *Conn) error {
+		calls++
+		if calls == 1 {
+			return fmt.Errorf("request failed: %w", ErrProtocol)
+		}
+		return nil
+	})
+	if err != nil || calls != 2 || backend.count() != 2 {
+		t.Errorf("Do returned %v after %d calls and %d connections\n", err, calls, backend.count())
+	}
+
+	calls = 0
+	err = pool.Do(context.Background(), "", func(conn *Conn) error {
+		calls++
+		return ErrUnknownJob
+	})
+	if !errors.Is(err, ErrUnknownJob) || calls != 1 {
+		t.Errorf("Do returned %v after %d calls\n", err, calls)
+	}
+}
+
+func TestPoolClose(t *testing.T) {
+	pool := &Pool{Backend: NewFakeServer("fake")}
+	conn, err := pool.Get(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Get failed: %s\n", err)
+	}
+	if err := pool.Close(); err != nil {
+		t.Errorf("Close failed: %s\n", err)
+	}
+	pool.Put(conn, nil)
+	if _, err := conn.StatServer(nil, ""); !errors.Is(err, ErrClosed) {
+		t.Errorf("Connection in use wasn't closed when put back: %v\n", err)
+	}
+	if _, err := pool.Get(context.Background(), ""); !errors.Is(err, ErrClosed) {
+		t.Errorf("Get after Close returned %v\n", err)
+	}
+}
@@ -0,0 +1,388 @@
+package pbs
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrUnsupportedOption is returned, wrapped in an *OptionError, for qsub
+// options which can't be converted to attributes
+var ErrUnsupportedOption = errors.New("unsupported option")
+
+// OptionError reports a qsub option, from a command line or a script
+// directive, which couldn't be used
+type OptionError struct {
+	// Line is the script line of the directive, zero for the command line
+	Line   int
+	Option string
+	Err    error
+}
+
+func (e *OptionError) Error() string {
+	msg := "pbs: "
+	if e.Line > 0 {
+		msg += "line " + strconv.Itoa(e.Line) + ": "
+	}
+	if e.Option != "" {
+		msg += e.Option + ": "
+	}
+	// The errors of this package's parsers have their own prefix
+	return msg + strings.TrimPrefix(e.Err.Error(), "pbs: ")
+}
+
+func (e *OptionError) Unwrap() error {
+	return e.Err
+}
+
+// QsubOptions are the job attributes and destination given by qsub's
+// options
+type QsubOptions struct {
+	Attribs     []Attrib
+	Destination string
+	// ExportEnv is set by -V, which adds the environment qsub is run in to
+	// Variable_List, see CaptureEnv
+	ExportEnv bool
+
+	// Script and DirectivePrefix, from -C, are only set from a command line
+	Script          string
+	DirectivePrefix string
+}
+
+// qsubAttribs are the options which set an attribute to their argument,
+// with a check of the argument
+var qsubAttribs = map[byte]struct {
+	name  string
+	check func(string) error
+}{
+	'A': {ATTR_A, nil},
+	'F': {ATTR_args, nil},
+	'M': {ATTR_M, nil},
+	'N': {ATTR_N, checkNotEmpty},
+	'P': {ATTR_P, checkNotEmpty},
+	'S': {ATTR_S, checkNotEmpty},
+	'c': {ATTR_c, checkNotEmpty},
+	'd': {ATTR_init_work_dir, checkNotEmpty},
+	'e': {ATTR_e, nil},
+	'j': {ATTR_j, checkOneOf("oe", "eo", "n")},
+	'k': {ATTR_k, checkOneOf("e", "o", "eo", "oe", "n")},
+	'm': {ATTR_m, checkMailPoints},
+	'o': {ATTR_o, nil},
+	'p': {ATTR_p, checkPriority},
+	'r': {ATTR_r, checkOneOf("y", "n")},
+	'u': {ATTR_u, checkNotEmpty},
+	't': {ATTR_t, func(s string) error {
+		r, err := ParseArrayRange(s)
+		if err == nil && r.hasSteps() {
+			err = errors.New("array range " + strconv.Quote(s) + " has steps, which TORQUE doesn't accept")
+		}
+		return err
+	}},
+}
+
+// qsubFlags are the options which don't take an argument
+const qsubFlags = "Vfhz"
+
+// qsubUnsupported are the qsub options which can't be converted, with the
+// reason why
+var qsubUnsupported = map[byte]string{
+	'I': "interactive jobs can't be submitted",
+	'X': "interactive jobs can't be submitted",
+	'x': "interactive jobs can't be submitted",
+	'a': "set the Execution_Time attribute to the time instead",
+	'b': "qsub's server timeout doesn't apply",
+	'D': "set the attribute with -W instead",
+	'K': "qsub's wait for the job doesn't apply",
+	'L': "set the resources with -l instead",
+	'T': "set the attribute with -W instead",
+	'w': "set the attribute with -W instead",
+}
+
+// supported returns an error for opt if it isn't a qsub option which can
+// be converted to attributes
+func supported(opt byte) error {
+	if _, ok := qsubAttribs[opt]; ok || strings.IndexByte(qsubFlags+"CWlqv", opt) >= 0 {
+		return nil
+	}
+	if reason, ok := qsubUnsupported[opt]; ok {
+		return fmt.Errorf("%w, %s", ErrUnsupportedOption, reason)
+	}
+	return ErrUnsupportedOption
+}
+
+func checkNotEmpty(s string) error {
+	if s == "" {
+		return errors.New("empty value")
+	}
+	return nil
+}
+
+func checkOneOf(values ...string) func(string) error {
+	return func(s string) error {
+		for _, v := range values {
+			if s == v {
+				return nil
+			}
+		}
+		return errors.New("invalid value " + strconv.Quote(s) + ", expected one of " + strings.Join(values, ", "))
+	}
+}
+
+func checkMailPoints(s string) error {
+	if s == "n" || s != "" && strings.Trim(s, "abe") == "" {
+		return nil
+	}
+	return errors.New("invalid mail points " + strconv.Quote(s))
+}
+
+func checkPriority(s string) error {
+	if n, err := strconv.Atoi(s); err != nil || n < -1024 || n > 1023 {
+		return errors.New("invalid priority " + strconv.Quote(s) + ", expected -1024 to 1023")
+	}
+	return nil
+}
+
+// set applies the option opt with its argument, replacing the value of an
+// earlier option for the same attribute or resource
+func (o *QsubOptions) set(opt byte, arg string, lookup func(string) (string, bool)) error {
+	switch opt {
+	case 'V':
+		o.ExportEnv = true
+	case 'f':
+		o.setAttrib(Attrib{Name: ATTR_f, Value: "true"})
+	case 'h':
+		o.setAttrib(Attrib{Name: ATTR_h, Value: string(USER_HOLD)})
+	case 'z':
+		// -z only stops qsub printing the job ID
+	case 'C':
+		o.DirectivePrefix = arg
+	case 'q':
+		if arg == "" {
+			return errors.New("empty destination")
+		}
+		o.Destination = arg
+	case 'l':
+		for _, res := range strings.Split(arg, ",") {
+			name, value, ok := strings.Cut(res, "=")
+			if !ok || name == "" {
+				return errors.New("invalid resource " + strconv.Quote(res))
+			}
+			o.setAttrib(Attrib{Name: ATTR_l, Resource: name, Value: value})
+		}
+	case 'v':
+		if err := checkNotEmpty(arg); err != nil {
+			return err
+		}
+		env, err := ParseEnvLookup(arg, lookup)
+		if err != nil {
+			return err
+		}
+		if a := attribValue(o.Attribs, ATTR_v, ""); a != nil {
+			previous, err := ParseEnv(a.Value)
+			if err != nil {
+				return err
+			}
+			env = previous.Merge(env)
+		}
+		o.setAttrib(env.Attrib())
+	case 'W':
+		for _, a := range splitAttribList(arg) {
+			name, value, ok := strings.Cut(a, "=")
+			if !ok || name == "" {
+				return errors.New("invalid attribute " + strconv.Quote(a))
+			}
+			switch name {
+			case ATTR_depend:
+				if _, err := ParseDependencies(value); err != nil {
+					return err
+				}
+			case ATTR_stagein, ATTR_stageout:
+				if _, err := ParseStageSpecs(value); err != nil {
+					return err
+				}
+			}
+			o.setAttrib(Attrib{Name: name, Value: value})
+		}
+	default:
+		attrib, ok := qsubAttribs[opt]
+		if !ok {
+			return ErrUnsupportedOption
+		}
+		if attrib.check != nil {
+			if err := attrib.check(arg); err != nil {
+				return err
+			}
+		}
+		o.setAttrib(Attrib{Name: attrib.name, Value: arg})
+	}
+	return nil
+}
+
+func (o *QsubOptions) setAttrib(a Attrib) {
+	o.Attribs = setAttribs(o.Attribs, []Attrib{a}, false)
+}
+
+// splitAttribList splits the name=value list of -W, in which a value can
+// have commas, e.g. "depend=afterok:1,before:2,umask=022"
+func splitAttribList(s string) []string {
+	var list []string
+	for _, part := range strings.Split(s, ",") {
+		if len(list) > 0 && !strings.Contains(part, "=") {
+			list[len(list)-1] += "," + part
+		} else {
+			list = append(list, part)
+		}
+	}
+	return list
+}
+
+// parseArgs applies the options in args, stopping at the first argument
+// which isn't an option, or after "--", and returns the remaining
+// arguments. As with getopt, flags can be combined and an argument can
+// follow its option directly, e.g. "-Vj oe" or "-Nname". The values of
+// variables given to -v without one are from lookup, if it isn't nil.
+func (o *QsubOptions) parseArgs(args []string, line int, lookup func(string) (string, bool)) ([]string, error) {
+	for len(args) > 0 {
+		arg := args[0]
+		if arg == "--" {
+			return args[1:], nil
+		}
+		if len(arg) < 2 || arg[0] != '-' {
+			return args, nil
+		}
+		args = args[1:]
+
+		for i := 1; i < len(arg); i++ {
+			opt := arg[i]
+			err := supported(opt)
+			if err == nil && opt == 'C' && line > 0 {
+				err = errors.New("only allowed on the command line")
+			}
+			if err != nil {
+				return nil, &OptionError{Line: line, Option: "-" + string(opt), Err: err}
+			}
+			if strings.IndexByte(qsubFlags, opt) >= 0 {
+				if err := o.set(opt, "", lookup); err != nil {
+					return nil, &OptionError{Line: line, Option: "-" + string(opt), Err: err}
+				}
+				continue
+			}
+
+			value := arg[i+1:]
+			if value == "" {
+				if len(args) == 0 {
+					return nil, &OptionError{Line: line, Option: "-" + string(opt), Err: errors.New("missing argument")}
+				}
+				value, args = args[0], args[1:]
+			}
+			if err := o.set(opt, value, lookup); err != nil {
+				return nil, &OptionError{Line: line, Option: "-" + string(opt), Err: err}
+			}
+			break
+		}
+	}
+	return nil, nil
+}
+
+// ParseQsubArgs parses the arguments of a qsub command line, without the
+// "qsub" itself, with the same options as ParseDirectives and the script
+// and -C. Options which qsub has but which can't be converted to
+// attributes, such as -I for interactive jobs, are rejected with an
+// *OptionError wrapping ErrUnsupportedOption.
+//
+// To use the directives in the script, as qsub does, parse them with the
+// DirectivePrefix and Merge them.
+//
+// Variables given to -v without a value, e.g. "-v HOME", have an empty
+// value; see ParseQsubArgsLookup.
+func ParseQsubArgs(args []string) (QsubOptions, error) {
+	return ParseQsubArgsLookup(args, nil)
+}
+
+// ParseQsubArgsLookup is ParseQsubArgs with the values of variables given
+// to -v without one from lookup, as qsub takes them from its environment,
+// e.g. with os.LookupEnv. Only use it for a command line from the user
+// whose environment lookup reads.
+func ParseQsubArgsLookup(args []string, lookup func(string) (string, bool)) (QsubOptions, error) {
+	var o QsubOptions
+	args, err := o.parseArgs(args, 0, lookup)
+	if err != nil {
+		return o, err
+	}
+	if len(args) > 0 {
+		o.Script, args = args[0], args[1:]
+	}
+	if len(args) > 0 {
+		return o, &OptionError{Err: errors.New("unexpected argument " + strconv.Quote(args[0]))}
+	}
+	return o, nil
+}
+
+// ParseQsubString parses qsub's arguments from a string, which is split
+// into words at spaces, with quotes and backslashes as in a shell, e.g.
+// "-l nodes=1:ppn=4 -N 'my job'"
+func ParseQsubString(s string) (QsubOptions, error) {
+	args, err := splitWords(s)
+	if err != nil {
+		return QsubOptions{}, &OptionError{Err: err}
+	}
+	return ParseQsubArgs(args)
+}
+
+// Merge returns the options of o added to those from directives, with o's
+// taking precedence as command line options do over a script's
+// directives. Each attribute, or resource of Resource_List, from o
+// replaces the one from directives.
+func (o QsubOptions) Merge(directives QsubOptions) QsubOptions {
+	merged := o
+	merged.Attribs = setAttribs(append([]Attrib(nil), directives.Attribs...), o.Attribs, false)
+	if merged.Destination == "" {
+		merged.Destination = directives.Destination
+	}
+	merged.ExportEnv = o.ExportEnv || directives.ExportEnv
+	return merged
+}
+
+// splitWords splits s into words at spaces, as a shell does, with single
+// and double quotes and backslashes to include spaces in words
+func splitWords(s string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+	for _, r := range s {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inWord = true, true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, errors.New("unterminated quote")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
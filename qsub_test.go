@@ -0,0 +1,116 @@
+package pbs
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseQsubArgs(t *testing.T) {
+	o, err := ParseQsubString(`-l nodes=1:ppn=4 -q long -N "my job" -W depend=afterok:12 -hVz -kn -C '#$' job.sh`)
+	if err != nil {
+		t.Fatalf("ParseQsubString failed: %s\n", err)
+	}
+	expected := QsubOptions{
+		Destination:     "long",
+		ExportEnv:       true,
+		Script:          "job.sh",
+		DirectivePrefix: "#$",
+		Attribs: []Attrib{
+			{Name: ATTR_l, Resource: "nodes", Value: "1:ppn=4"},
+			{Name: ATTR_N, Value: "my job"},
+			{Name: ATTR_depend, Value: "afterok:12"},
+			{Name: ATTR_h, Value: "u"},
+			{Name: ATTR_k, Value: "n"},
+		},
+	}
+	if !reflect.DeepEqual(o, expected) {
+		t.Errorf("ParseQsubString returned\n%+v\nexpected\n%+v\n", o, expected)
+	}
+
+	o, err = ParseQsubArgs([]string{"-N", "name", "--", "-script.sh"})
+	if err != nil || o.Script != "-script.sh" {
+		t.Errorf("ParseQsubArgs with -- returned %+v, %v\n", o, err)
+	}
+	o, err = ParseQsubArgs(nil)
+	if err != nil || !reflect.DeepEqual(o, QsubOptions{}) {
+		t.Errorf("ParseQsubArgs(nil) returned %+v, %v\n", o, err)
+	}
+
+	// Only ParseQsubArgsLookup gives -v variables values from the
+	// environment, and never for directives
+	lookup := func(name string) (string, bool) {
+		return map[string]string{"SECRET": "s3cr3t"}[name], name == "SECRET"
+	}
+	args := []string{"-v", "A=1,SECRET", "-v", "B"}
+	if o, err := ParseQsubArgs(args); err != nil || o.Attribs[0].Value != "A=1,B=,SECRET=" {
+		t.Errorf("ParseQsubArgs with -v returned %+v, %v\n", o, err)
+	}
+	if o, err := ParseQsubArgsLookup(args, lookup); err != nil || o.Attribs[0].Value != "A=1,B=,SECRET=s3cr3t" {
+		t.Errorf("ParseQsubArgsLookup with -v returned %+v, %v\n", o, err)
+	}
+	t.Setenv("PBS_TEST_SECRET", "s3cr3t")
+	if o, err := ParseDirectives(strings.NewReader("#PBS -v PBS_TEST_SECRET\n"), ""); err != nil || o.Attribs[0].Value != "PBS_TEST_SECRET=" {
+		t.Errorf("ParseDirectives with -v returned %+v, %v\n", o, err)
+	}
+}
+
+func TestParseQsubArgsErrors(t *testing.T) {
+	tests := []struct {
+		args string
+		msg  string
+	}{
+		{"-I", "pbs: -I: unsupported option, interactive jobs can't be submitted"},
+		{"-N a -a 1200 job.sh", "pbs: -a: unsupported option, set the Execution_Time attribute to the time instead"},
+		{"-Vy", "pbs: -y: unsupported option"},
+		{"-l", "pbs: -l: missing argument"},
+		{"-k x", `pbs: -k: invalid value "x", expected one of e, o, eo, oe, n`},
+		{"job.sh extra", `pbs: unexpected argument "extra"`},
+		{`-N "a`, "pbs: unterminated quote"},
+	}
+	for _, test := range tests {
+		_, err := ParseQsubString(test.args)
+		if err == nil || err.Error() != test.msg {
+			t.Errorf("ParseQsubString(%q) returned %v, expected %s\n", test.args, err, test.msg)
+		}
+	}
+
+	_, err := ParseQsubString("-X")
+	var oerr *OptionError
+	if !errors.As(err, &oerr) || oerr.Line != 0 || oerr.Option != "-X" || !errors.Is(err, ErrUnsupportedOption) {
+		t.Errorf("ParseQsubString returned %#v\n", err)
+	}
+}
+
+func TestQsubMerge(t *testing.T) {
+	script := "#!/bin/sh\n#PBS -N script -q short -V\n#PBS -l nodes=2,walltime=01:00:00 -v A=1\n#PBS -m abe\n"
+	directives, err := ParseDirectives(strings.NewReader(script), "")
+	if err != nil {
+		t.Fatalf("ParseDirectives failed: %s\n", err)
+	}
+	cmdline, err := ParseQsubString("-N cmdline -l walltime=02:00:00 -v B=2 job.sh")
+	if err != nil {
+		t.Fatalf("ParseQsubString failed: %s\n", err)
+	}
+
+	o := cmdline.Merge(directives)
+	expected := QsubOptions{
+		Destination: "short",
+		ExportEnv:   true,
+		Script:      "job.sh",
+		Attribs: []Attrib{
+			{Name: ATTR_l, Resource: "nodes", Value: "2"},
+			{Name: ATTR_m, Value: "abe"},
+			{Name: ATTR_N, Value: "cmdline"},
+			{Name: ATTR_l, Resource: "walltime", Value: "02:00:00"},
+			{Name: ATTR_v, Value: "B=2"},
+		},
+	}
+	if !reflect.DeepEqual(o, expected) {
+		t.Errorf("Merge returned\n%+v\nexpected\n%+v\n", o, expected)
+	}
+	if len(directives.Attribs) != 5 {
+		t.Errorf("Merge modified the directives to %+v\n", directives.Attribs)
+	}
+}
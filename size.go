@@ -0,0 +1,96 @@
+package pbs
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Size is an amount of memory or disk in bytes, as used by resources such
+// as mem, vmem and file. TORQUE's units are powers of 1024.
+type Size int64
+
+// Size units
+const (
+	Byte     Size = 1
+	Kilobyte      = 1024 * Byte
+	Megabyte      = 1024 * Kilobyte
+	Gigabyte      = 1024 * Megabyte
+	Terabyte      = 1024 * Gigabyte
+	Petabyte      = 1024 * Terabyte
+)
+
+// WordSize is the size of the words used by the "w" units
+const WordSize = 8 * Byte
+
+var sizeUnits = []struct {
+	suffix string
+	size   Size
+}{
+	{"pb", Petabyte},
+	{"tb", Terabyte},
+	{"gb", Gigabyte},
+	{"mb", Megabyte},
+	{"kb", Kilobyte},
+	{"b", Byte},
+}
+
+var sizePrefixes = map[byte]Size{
+	'k': Kilobyte,
+	'm': Megabyte,
+	'g': Gigabyte,
+	't': Terabyte,
+	'p': Petabyte,
+}
+
+// ParseSize parses a size such as "512mb", "16gb" or "100", which is in
+// bytes. The units can be in words instead of bytes, e.g. "64mw", see
+// WordSize. Units are case insensitive, and the "b" can be left off.
+func ParseSize(s string) (Size, error) {
+	orig := s
+	s = strings.ToLower(strings.TrimSpace(s))
+	unit := Byte
+	if strings.HasSuffix(s, "w") {
+		s, unit = s[:len(s)-1], WordSize
+	} else {
+		s = strings.TrimSuffix(s, "b")
+	}
+	if len(s) > 0 {
+		if prefix, ok := sizePrefixes[s[len(s)-1]]; ok {
+			s, unit = s[:len(s)-1], unit*prefix
+		}
+	}
+
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, errors.New("pbs: invalid size " + strconv.Quote(orig))
+	}
+	if n > int64(1<<63-1)/int64(unit) {
+		return 0, errors.New("pbs: size " + strconv.Quote(orig) + " out of range")
+	}
+	return Size(n) * unit, nil
+}
+
+// String formats s in the largest unit which represents it exactly, e.g.
+// "16gb"
+func (s Size) String() string {
+	for _, u := range sizeUnits {
+		if s != 0 && s%u.size == 0 {
+			return strconv.FormatInt(int64(s/u.size), 10) + u.suffix
+		}
+	}
+	return strconv.FormatInt(int64(s), 10) + "b"
+}
+
+func (s Size) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *Size) UnmarshalText(text []byte) error {
+	v, err := ParseSize(string(text))
+	if err != nil {
+		return err
+	}
+	*s = v
+	return nil
+}
@@ -0,0 +1,248 @@
+package pbs
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// StageSpec is a file to stage in or out, as in the stagein and stageout
+// attributes. Local is the path on the execution host, Remote the path on
+// Host, e.g. "/tmp/input@storage:/data/input".
+type StageSpec struct {
+	Local  string
+	Host   string
+	Remote string
+}
+
+// ParseStageSpec parses a "local@host:remote" file specification
+func ParseStageSpec(s string) (StageSpec, error) {
+	local, rest, ok := strings.Cut(s, "@")
+	if !ok {
+		return StageSpec{}, errors.New("pbs: missing host in stage file " + strconv.Quote(s))
+	}
+	host, remote, ok := strings.Cut(rest, ":")
+	if !ok {
+		return StageSpec{}, errors.New("pbs: missing remote path in stage file " + strconv.Quote(s))
+	}
+	spec := StageSpec{Local: local, Host: host, Remote: remote}
+	if err := spec.Validate(); err != nil {
+		return StageSpec{}, err
+	}
+	return spec, nil
+}
+
+// Validate checks that spec has each of its parts, and that they can be
+// written in a stage file list: there's no way to escape a comma, or an @
+// in the local path
+func (spec StageSpec) Validate() error {
+	msg := ""
+	switch {
+	case spec.Local == "":
+		msg = "missing local path"
+	case spec.Host == "":
+		msg = "missing host"
+	case spec.Remote == "":
+		msg = "missing remote path"
+	case strings.Contains(spec.Local, "@"):
+		msg = "@ in local path"
+	case strings.ContainsAny(spec.Host, "@:/ \t"):
+		msg = "invalid host " + strconv.Quote(spec.Host)
+	case strings.Contains(spec.Local+spec.Host+spec.Remote, ","):
+		msg = "comma in path"
+	default:
+		return nil
+	}
+	return errors.New("pbs: stage file " + strconv.Quote(spec.String()) + ": " + msg)
+}
+
+// String formats spec as local@host:remote
+func (spec StageSpec) String() string {
+	return spec.Local + "@" + spec.Host + ":" + spec.Remote
+}
+
+// StageSpecs is a list of files to stage, the value of the stagein and
+// stageout attributes
+type StageSpecs []StageSpec
+
+// ParseStageSpecs parses a comma separated list of stage files. An empty
+// list is nil.
+func ParseStageSpecs(s string) (StageSpecs, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var specs StageSpecs
+	for _, item := range strings.Split(s, ",") {
+		spec, err := ParseStageSpec(strings.TrimSpace(item))
+		if err != nil {
+			return nil, err
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+// Validate checks each of the files
+func (specs StageSpecs) Validate() error {
+	for _, spec := range specs {
+		if err := spec.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// String formats specs as a comma separated list
+func (specs StageSpecs) String() string {
+	items := make([]string, len(specs))
+	for i, spec := range specs {
+		items[i] = spec.String()
+	}
+	return strings.Join(items, ",")
+}
+
+// StageIn returns specs as the stagein attribute, to copy the files to
+// the execution host before the job runs
+func (specs StageSpecs) StageIn() Attrib {
+	return Attrib{Name: ATTR_stagein, Value: specs.String()}
+}
+
+// StageOut returns specs as the stageout attribute, to copy the files from
+// the execution host after the job has run
+func (specs StageSpecs) StageOut() Attrib {
+	return Attrib{Name: ATTR_stageout, Value: specs.String()}
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (specs StageSpecs) MarshalText() ([]byte, error) {
+	return []byte(specs.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (specs *StageSpecs) UnmarshalText(text []byte) error {
+	v, err := ParseStageSpecs(string(text))
+	if err != nil {
+		return err
+	}
+	*specs = v
+	return nil
+}
+
+// AddStaging returns attribs with the stagein and stageout attributes set
+// to in and out, replacing any already in attribs. Empty lists are left
+// out.
+func AddStaging(attribs []Attrib, in StageSpecs, out StageSpecs) ([]Attrib, error) {
+	var staging []Attrib
+	for _, list := range []struct {
+		specs  StageSpecs
+		attrib func(StageSpecs) Attrib
+	}{
+		{in, StageSpecs.StageIn},
+		{out, StageSpecs.StageOut},
+	} {
+		if len(list.specs) == 0 {
+			continue
+		}
+		if err := list.specs.Validate(); err != nil {
+			return nil, err
+		}
+		staging = append(staging, list.attrib(list.specs))
+	}
+	return setAttribs(append([]Attrib(nil), attribs...), staging, false), nil
+}
+
+// StageStatus is how far the staging of a job's files has got, as far as
+// the server reports it with the job's substate. The server doesn't report
+// on each file, so the files of a list are staged together.
+type StageStatus int
+
+// Stage statuses
+const (
+	StagePending StageStatus = iota
+	StagingIn
+	StagedIn
+	StageInFailed
+	StagingOut
+	StagedOut
+)
+
+var stageStatusNames = map[StageStatus]string{
+	StagePending:  "pending",
+	StagingIn:     "staging in",
+	StagedIn:      "staged in",
+	StageInFailed: "stage in failed",
+	StagingOut:    "staging out",
+	StagedOut:     "staged out",
+}
+
+func (s StageStatus) String() string {
+	if name, ok := stageStatusNames[s]; ok {
+		return name
+	}
+	return "StageStatus(" + strconv.Itoa(int(s)) + ")"
+}
+
+// stageStatuses are the substates after which files have been staged, or
+// are being; the others are StagePending
+var stageStatuses = map[JobSubstate]StageStatus{
+	SubstateStageIn:       StagingIn,
+	SubstateStageGo:       StagingIn,
+	SubstateStageComplete: StagedIn,
+	SubstateStageFail:     StageInFailed,
+	SubstatePrerun:        StagedIn,
+	SubstateStarting:      StagedIn,
+	SubstateRunning:       StagedIn,
+	SubstateSuspend:       StagedIn,
+	SubstateExiting:       StagedIn,
+	SubstateAbort:         StagedIn,
+	SubstateNoTermRequeue: StagedIn,
+	SubstateRerun:         StagedIn,
+	SubstateStageOut:      StagingOut,
+	SubstateRerun1:        StagingOut,
+	SubstateStageDelete:   StagedOut,
+	SubstateRerun2:        StagedOut,
+	SubstateRerun3:        StagedOut,
+	SubstateExited:        StagedOut,
+	SubstatePreObit:       StagedOut,
+	SubstateObit:          StagedOut,
+	SubstateComplete:      StagedOut,
+}
+
+// Staging is the files of a job to stage, and how far the staging has got
+type Staging struct {
+	In     StageSpecs
+	Out    StageSpecs
+	Status StageStatus
+}
+
+// Staged returns the files which have been staged in and out so far
+func (s Staging) Staged() StageSpecs {
+	var staged StageSpecs
+	if s.Status >= StagedIn && s.Status != StageInFailed {
+		staged = append(staged, s.In...)
+	}
+	if s.Status == StagedOut {
+		staged = append(staged, s.Out...)
+	}
+	return staged
+}
+
+// Staging parses the job's stagein and stageout attributes, with the
+// status of the staging from its substate. A job which completed without
+// running, e.g. because it was deleted while queued, never staged its
+// files and is StagePending.
+func (j Job) Staging() (Staging, error) {
+	in, err := ParseStageSpecs(j.Extra[ATTR_stagein])
+	if err != nil {
+		return Staging{}, err
+	}
+	out, err := ParseStageSpecs(j.Extra[ATTR_stageout])
+	if err != nil {
+		return Staging{}, err
+	}
+	status := stageStatuses[j.Substate]
+	if j.State == StateComplete && j.StartTime.IsZero() {
+		status = StagePending
+	}
+	return Staging{In: in, Out: out, Status: status}, nil
+}
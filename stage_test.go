@@ -0,0 +1,112 @@
+package pbs
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStageSpecs(t *testing.T) {
+	s := "/tmp/in.dat@storage:/data/in.dat,results@storage.example.com:/data/run:1/results"
+	specs, err := ParseStageSpecs(s)
+	if err != nil {
+		t.Fatalf("ParseStageSpecs failed: %s\n", err)
+	}
+	expected := StageSpecs{
+		{Local: "/tmp/in.dat", Host: "storage", Remote: "/data/in.dat"},
+		{Local: "results", Host: "storage.example.com", Remote: "/data/run:1/results"},
+	}
+	if !reflect.DeepEqual(specs, expected) {
+		t.Errorf("ParseStageSpecs returned %+v\n", specs)
+	}
+	if specs.String() != s {
+		t.Errorf("%q formatted as %q\n", s, specs.String())
+	}
+	if a := specs[:1].StageIn(); a != (Attrib{Name: ATTR_stagein, Value: "/tmp/in.dat@storage:/data/in.dat"}) {
+		t.Errorf("StageIn returned %+v\n", a)
+	}
+	if specs, err := ParseStageSpecs(""); specs != nil || err != nil {
+		t.Errorf("ParseStageSpecs of an empty list returned %+v, %v\n", specs, err)
+	}
+
+	for _, bad := range []string{"in.dat", "in.dat@storage", "@storage:/in.dat", "in.dat@:/in.dat", "in.dat@storage:", "a@b@storage:/x", "in.dat@stor age:/x", "a@h:/x,,b@h:/y"} {
+		if _, err := ParseStageSpecs(bad); err == nil {
+			t.Errorf("ParseStageSpecs(%q) didn't fail\n", bad)
+		}
+	}
+	if err := (StageSpec{Local: "a,b", Host: "h", Remote: "/x"}).Validate(); err == nil {
+		t.Errorf("Validate of a path with a comma didn't fail\n")
+	}
+}
+
+func TestAddStaging(t *testing.T) {
+	in := StageSpecs{{Local: "in", Host: "h", Remote: "/in"}}
+	out := StageSpecs{{Local: "out", Host: "h", Remote: "/out"}}
+	attribs := []Attrib{{Name: ATTR_N, Value: "job"}, {Name: ATTR_stagein, Value: "old@h:/old"}}
+
+	got, err := AddStaging(attribs, in, out)
+	if err != nil {
+		t.Fatalf("AddStaging failed: %s\n", err)
+	}
+	expected := []Attrib{
+		{Name: ATTR_N, Value: "job"},
+		{Name: ATTR_stagein, Value: "in@h:/in"},
+		{Name: ATTR_stageout, Value: "out@h:/out"},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("AddStaging returned %+v\n", got)
+	}
+	if attribs[1].Value != "old@h:/old" {
+		t.Errorf("AddStaging modified its argument\n")
+	}
+	if _, err := AddStaging(nil, StageSpecs{{Local: "in"}}, nil); err == nil {
+		t.Errorf("AddStaging of an invalid file didn't fail\n")
+	}
+}
+
+func TestJobStaging(t *testing.T) {
+	server := NewFakeServer("fake")
+	client := connectFake(t, server)
+
+	in := StageSpecs{{Local: "in", Host: "h", Remote: "/in"}}
+	out := StageSpecs{{Local: "out", Host: "h", Remote: "/out"}}
+	attribs, _ := AddStaging(nil, in, out)
+	jobid, err := client.Submit(attribs, "job.sh", "", "")
+	if err != nil {
+		t.Fatalf("Submit failed: %s\n", err)
+	}
+
+	staging := func() Staging {
+		jobs, err := StatJobs(client, jobid, "")
+		if err != nil {
+			t.Fatalf("StatJobs failed: %s\n", err)
+		}
+		s, err := jobs[0].Staging()
+		if err != nil {
+			t.Fatalf("Staging failed: %s\n", err)
+		}
+		return s
+	}
+
+	s := staging()
+	if !reflect.DeepEqual(s, Staging{In: in, Out: out, Status: StagePending}) || s.Staged() != nil {
+		t.Errorf("Staging of a queued job returned %+v\n", s)
+	}
+	server.Advance(0)
+	if s := staging(); s.Status != StagedIn || !reflect.DeepEqual(s.Staged(), in) {
+		t.Errorf("Staging of a running job returned %+v\n", s)
+	}
+	server.Advance(time.Hour)
+	if s := staging(); s.Status != StagedOut || len(s.Staged()) != 2 {
+		t.Errorf("Staging of a completed job returned %+v\n", s)
+	}
+
+	failed, _ := Job{State: StateHeld, Substate: SubstateStageFail}.Staging()
+	if failed.Status != StageInFailed || failed.Status.String() != "stage in failed" {
+		t.Errorf("Staging of a job which failed to stage in returned %+v\n", failed)
+	}
+	deleted, _ := Job{State: StateComplete, Substate: SubstateComplete}.Staging()
+	if deleted.Status != StagePending {
+		t.Errorf("Staging of a job which never ran returned %+v\n", deleted)
+	}
+}
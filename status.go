@@ -0,0 +1,346 @@
+package pbs
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Node is the status of a node, decoded from the BatchStatus returned by
+// Pbs_statnode
+type Node struct {
+	Name string
+
+	// State is the list of states, e.g. "job-exclusive" or "down,offline"
+	State      []string
+	NP         int
+	Properties []string
+	NType      string
+
+	// Jobs are the jobs running on the node, as slot/jobid
+	Jobs []string
+
+	// Status is the node's report of itself, from the MOM
+	Status map[string]string
+	GPUs   int
+
+	// Extra holds the attributes without a field, as for Job
+	Extra map[string]string
+}
+
+// HasState reports whether state is one of the node's states
+func (n Node) HasState(state string) bool {
+	for _, s := range n.State {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+// StateCount is the number of jobs in each state, from a queue's or the
+// server's state_count attribute
+type StateCount struct {
+	Transit  int
+	Queued   int
+	Held     int
+	Waiting  int
+	Running  int
+	Exiting  int
+	Complete int
+}
+
+// ParseStateCount parses a state_count value, e.g. "Transit:0 Queued:2
+// Held:0 Waiting:0 Running:1 Exiting:0 Complete:0"
+func ParseStateCount(s string) (StateCount, error) {
+	var c StateCount
+	for _, field := range strings.Fields(s) {
+		name, value, ok := strings.Cut(field, ":")
+		if !ok {
+			return c, errors.New("missing count")
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return c, err
+		}
+
+		switch name {
+		case "Transit":
+			c.Transit = n
+		case "Queued":
+			c.Queued = n
+		case "Held":
+			c.Held = n
+		case "Waiting":
+			c.Waiting = n
+		case "Running":
+			c.Running = n
+		case "Exiting":
+			c.Exiting = n
+		case "Complete":
+			c.Complete = n
+		}
+	}
+	return c, nil
+}
+
+// add counts a job in state. Suspended jobs are counted as running, as
+// pbs_server does.
+func (c *StateCount) add(state JobState) {
+	switch state {
+	case StateTransit:
+		c.Transit++
+	case StateQueued:
+		c.Queued++
+	case StateHeld:
+		c.Held++
+	case StateWaiting:
+		c.Waiting++
+	case StateRunning, StateSuspended:
+		c.Running++
+	case StateExiting:
+		c.Exiting++
+	case StateComplete:
+		c.Complete++
+	}
+}
+
+// Queue is the status of a queue, decoded from the BatchStatus returned by
+// Pbs_statque
+type Queue struct {
+	Name string
+
+	// Type is "Execution" or "Route"
+	Type       string
+	Enabled    bool
+	Started    bool
+	TotalJobs  int
+	StateCount StateCount
+
+	// MaxRunning is zero when there is no limit
+	MaxRunning int
+
+	ResourcesDefault map[string]string
+	ResourcesMax     map[string]string
+
+	// Extra holds the attributes without a field, as for Job
+	Extra map[string]string
+}
+
+// Server is the status of a server, decoded from the BatchStatus returned
+// by Pbs_statserver
+type Server struct {
+	Name string
+
+	// State is e.g. "Active" or "Idle"
+	State        string
+	Scheduling   bool
+	DefaultQueue string
+	TotalJobs    int
+	StateCount   StateCount
+
+	// The limits are zero when there is no limit
+	MaxRunning      int
+	MaxUserRun      int
+	MaxUserQueuable int
+
+	ResourcesDefault map[string]string
+	ResourcesMax     map[string]string
+
+	// Extra holds the attributes without a field, as for Job
+	Extra map[string]string
+}
+
+// extraKey is the key for a in the Extra maps
+func extraKey(a Attrib) string {
+	if a.Resource != "" {
+		return a.Name + "." + a.Resource
+	}
+	return a.Name
+}
+
+// setMapValue sets key in *m, making the map if necessary
+func setMapValue(m *map[string]string, key string, value string) {
+	if *m == nil {
+		*m = map[string]string{}
+	}
+	(*m)[key] = value
+}
+
+// parseAttribBool parses a boolean attribute, TORQUE accepts any case of
+// true/false, t/f, yes/no, y/n and 1/0
+func parseAttribBool(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "true", "t", "yes", "y", "1":
+		return true, nil
+	case "false", "f", "no", "n", "0":
+		return false, nil
+	}
+	return false, errors.New("not a boolean")
+}
+
+// splitList splits a comma separated attribute value
+func splitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// DecodeNode decodes a node's status
+func DecodeNode(b BatchStatus) (Node, error) {
+	node := Node{Name: b.Name}
+	for _, a := range b.Attributes {
+		var err error
+		switch a.Name {
+		case "state":
+			node.State = splitList(a.Value)
+		case "np":
+			node.NP, err = strconv.Atoi(a.Value)
+		case "properties":
+			node.Properties = splitList(a.Value)
+		case "ntype":
+			node.NType = a.Value
+		case "jobs":
+			node.Jobs = splitList(a.Value)
+		case "status":
+			for _, item := range splitList(a.Value) {
+				key, value, _ := strings.Cut(item, "=")
+				setMapValue(&node.Status, key, value)
+			}
+		case "gpus":
+			node.GPUs, err = strconv.Atoi(a.Value)
+		default:
+			setMapValue(&node.Extra, extraKey(a), a.Value)
+		}
+		if err != nil {
+			return node, attribError(b.Name, a, err)
+		}
+	}
+	return node, nil
+}
+
+// DecodeQueue decodes a queue's status
+func DecodeQueue(b BatchStatus) (Queue, error) {
+	queue := Queue{Name: b.Name}
+	for _, a := range b.Attributes {
+		var err error
+		switch a.Name {
+		case "queue_type":
+			queue.Type = a.Value
+		case "enabled":
+			queue.Enabled, err = parseAttribBool(a.Value)
+		case "started":
+			queue.Started, err = parseAttribBool(a.Value)
+		case ATTR_total:
+			queue.TotalJobs, err = strconv.Atoi(a.Value)
+		case "state_count":
+			queue.StateCount, err = ParseStateCount(a.Value)
+		case ATTR_maxrun:
+			queue.MaxRunning, err = strconv.Atoi(a.Value)
+		case "resources_default":
+			setMapValue(&queue.ResourcesDefault, a.Resource, a.Value)
+		case "resources_max":
+			setMapValue(&queue.ResourcesMax, a.Resource, a.Value)
+		default:
+			setMapValue(&queue.Extra, extraKey(a), a.Value)
+		}
+		if err != nil {
+			return queue, attribError(b.Name, a, err)
+		}
+	}
+	return queue, nil
+}
+
+// DecodeServer decodes a server's status
+func DecodeServer(b BatchStatus) (Server, error) {
+	server := Server{Name: b.Name}
+	for _, a := range b.Attributes {
+		var err error
+		switch a.Name {
+		case "server_state":
+			server.State = a.Value
+		case "scheduling":
+			server.Scheduling, err = parseAttribBool(a.Value)
+		case "default_queue":
+			server.DefaultQueue = a.Value
+		case ATTR_total:
+			server.TotalJobs, err = strconv.Atoi(a.Value)
+		case "state_count":
+			server.StateCount, err = ParseStateCount(a.Value)
+		case ATTR_maxrun:
+			server.MaxRunning, err = strconv.Atoi(a.Value)
+		case "max_user_run":
+			server.MaxUserRun, err = strconv.Atoi(a.Value)
+		case "max_user_queuable":
+			server.MaxUserQueuable, err = strconv.Atoi(a.Value)
+		case "resources_default":
+			setMapValue(&server.ResourcesDefault, a.Resource, a.Value)
+		case "resources_max":
+			setMapValue(&server.ResourcesMax, a.Resource, a.Value)
+		default:
+			setMapValue(&server.Extra, extraKey(a), a.Value)
+		}
+		if err != nil {
+			return server, attribError(b.Name, a, err)
+		}
+	}
+	return server, nil
+}
+
+// StatNodes returns the status of the node id, or of all nodes if id is
+// empty, as with Client.StatNode
+func StatNodes(c Client, id string, extend string) ([]Node, error) {
+	batch, err := c.StatNode(id, nil, extend)
+	if err != nil {
+		return nil, err
+	}
+	nodes := make([]Node, 0, len(batch))
+	for _, b := range batch {
+		node, err := DecodeNode(b)
+		if err != nil {
+			return nodes, err
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+// StatQueues returns the status of the queue id, or of all queues if id is
+// empty, as with Client.StatQue
+func StatQueues(c Client, id string, extend string) ([]Queue, error) {
+	batch, err := c.StatQue(id, nil, extend)
+	if err != nil {
+		return nil, err
+	}
+	queues := make([]Queue, 0, len(batch))
+	for _, b := range batch {
+		queue, err := DecodeQueue(b)
+		if err != nil {
+			return queues, err
+		}
+		queues = append(queues, queue)
+	}
+	return queues, nil
+}
+
+// StatServers returns the status of the server connected to, as with
+// Client.StatServer, which returns one entry
+func StatServers(c Client, extend string) ([]Server, error) {
+	batch, err := c.StatServer(nil, extend)
+	if err != nil {
+		return nil, err
+	}
+	servers := make([]Server, 0, len(batch))
+	for _, b := range batch {
+		server, err := DecodeServer(b)
+		if err != nil {
+			return servers, err
+		}
+		servers = append(servers, server)
+	}
+	return servers, nil
+}
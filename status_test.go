@@ -0,0 +1,122 @@
+package pbs
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStatNodes(t *testing.T) {
+	s := NewFakeServer("fake")
+	s.AddNode("node01", 2, "bigmem", "ssd")
+	s.AddNode("node02", 1)
+	client := connectFake(t, s)
+
+	id, _ := client.Submit(nil, "test.sh", "", "")
+	s.Advance(0)
+
+	nodes, err := StatNodes(client, "", "")
+	if err != nil {
+		t.Fatalf("StatNodes failed: %s\n", err)
+	}
+	if len(nodes) != 2 {
+		t.Fatalf("StatNodes returned %d nodes\n", len(nodes))
+	}
+	node := nodes[0]
+	if node.Name != "node01" || node.NP != 2 || node.NType != "cluster" || !node.HasState("free") {
+		t.Errorf("Decoded node %+v\n", node)
+	}
+	if !reflect.DeepEqual(node.Properties, []string{"bigmem", "ssd"}) {
+		t.Errorf("Node properties %q\n", node.Properties)
+	}
+	if !reflect.DeepEqual(node.Jobs, []string{"0/" + id}) {
+		t.Errorf("Node jobs %q\n", node.Jobs)
+	}
+}
+
+func TestDecodeNode(t *testing.T) {
+	node, err := DecodeNode(BatchStatus{Name: "node03", Attributes: []Attrib{
+		{Name: "state", Value: "down,offline"},
+		{Name: "status", Value: "rectime=1400000000,ncpus=16,physmem=65536kb,jobs=,state=free"},
+		{Name: "gpus", Value: "2"},
+		{Name: "note", Value: "disk replaced"},
+	}})
+	if err != nil {
+		t.Fatalf("DecodeNode failed: %s\n", err)
+	}
+	if !node.HasState("offline") || node.HasState("free") || node.GPUs != 2 {
+		t.Errorf("Decoded node %+v\n", node)
+	}
+	if node.Status["ncpus"] != "16" || node.Status["jobs"] != "" || len(node.Status) != 5 {
+		t.Errorf("Node status %v\n", node.Status)
+	}
+	if node.Extra["note"] != "disk replaced" {
+		t.Errorf("Extra attributes %v\n", node.Extra)
+	}
+
+	if _, err := DecodeNode(BatchStatus{Attributes: []Attrib{{Name: "np", Value: "many"}}}); err == nil {
+		t.Errorf("Decoding np=many didn't fail\n")
+	}
+}
+
+func TestStatQueues(t *testing.T) {
+	s := NewFakeServer("fake")
+	client := connectFake(t, s)
+	err := client.Manager(MGR_CMD_SET, MGR_OBJ_QUEUE, "batch", []Attrib{
+		{Name: ATTR_maxrun, Value: "10"},
+		{Name: "resources_default", Resource: "walltime", Value: "01:00:00"},
+		{Name: "resources_max", Resource: "walltime", Value: "48:00:00"},
+	}, "")
+	if err != nil {
+		t.Fatalf("Setting queue attributes failed: %s\n", err)
+	}
+	client.Submit(nil, "test.sh", "", "")
+	client.Submit(nil, "test.sh", "", "")
+
+	queues, err := StatQueues(client, "batch", "")
+	if err != nil || len(queues) != 1 {
+		t.Fatalf("StatQueues returned %v, %v\n", queues, err)
+	}
+	q := queues[0]
+	if q.Type != "Execution" || !q.Enabled || !q.Started || q.MaxRunning != 10 || q.TotalJobs != 2 {
+		t.Errorf("Decoded queue %+v\n", q)
+	}
+	if q.StateCount.Queued != 2 {
+		t.Errorf("Queue state count %+v\n", q.StateCount)
+	}
+	if q.ResourcesDefault["walltime"] != "01:00:00" || q.ResourcesMax["walltime"] != "48:00:00" {
+		t.Errorf("Queue resources %v, %v\n", q.ResourcesDefault, q.ResourcesMax)
+	}
+}
+
+func TestStatServers(t *testing.T) {
+	s := NewFakeServer("fake")
+	client := connectFake(t, s)
+	client.Manager(MGR_CMD_SET, MGR_OBJ_SERVER, "", []Attrib{{Name: "max_user_run", Value: "5"}}, "")
+	client.Submit(nil, "test.sh", "", "")
+	s.Advance(0)
+
+	servers, err := StatServers(client, "")
+	if err != nil || len(servers) != 1 {
+		t.Fatalf("StatServers returned %v, %v\n", servers, err)
+	}
+	server := servers[0]
+	if server.Name != "fake" || server.State != "Active" || !server.Scheduling || server.DefaultQueue != "batch" {
+		t.Errorf("Decoded server %+v\n", server)
+	}
+	if server.MaxUserRun != 5 || server.TotalJobs != 1 || server.StateCount.Running != 1 {
+		t.Errorf("Decoded server %+v\n", server)
+	}
+}
+
+func TestParseStateCount(t *testing.T) {
+	c, err := ParseStateCount("Transit:1 Queued:2 Held:3 Waiting:4 Running:5 Exiting:6 Complete:7 ")
+	expected := StateCount{1, 2, 3, 4, 5, 6, 7}
+	if err != nil || c != expected {
+		t.Errorf("ParseStateCount returned %+v, %v\n", c, err)
+	}
+	for _, bad := range []string{"Queued", "Queued:x"} {
+		if _, err := ParseStateCount(bad); err == nil {
+			t.Errorf("ParseStateCount(%q) didn't fail\n", bad)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package pbs
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// MaxScriptSize is the largest job script which SubmitBytes and
+// SubmitReader will submit
+var MaxScriptSize = 16 << 20
+
+// ErrScriptTooLarge is returned for job scripts larger than MaxScriptSize
+var ErrScriptTooLarge = errors.New("pbs: job script is too large")
+
+// scriptSubmitter is implemented by the Clients which can send a job
+// script's contents to the server without it being in a file
+type scriptSubmitter interface {
+	SubmitScript(attribs []Attrib, script []byte, destination string, extend string) (string, error)
+}
+
+// SubmitBytes submits a job with the given script contents, rather than
+// the path of a script as Client.Submit takes. Clients which send the
+// script to the server themselves, such as those of the DIS Backend, are
+// given it directly; for the others it's written to a temporary file which
+// is removed once the job is submitted, or has failed to be.
+//
+// As with a script given to qsub on its standard input, the job is called
+// STDIN unless attribs has a Job_Name.
+func SubmitBytes(c Client, attribs []Attrib, script []byte, destination string, extend string) (string, error) {
+	if len(script) > MaxScriptSize {
+		return "", ErrScriptTooLarge
+	}
+	if attribValue(attribs, ATTR_N, "") == nil {
+		attribs = append(attribs[:len(attribs):len(attribs)], Attrib{Name: ATTR_N, Value: "STDIN"})
+	}
+	if s, ok := c.(scriptSubmitter); ok {
+		return s.SubmitScript(attribs, script, destination, extend)
+	}
+	return submitTempFile(c, attribs, script, destination, extend)
+}
+
+// SubmitReader is SubmitBytes with the script read from r
+func SubmitReader(c Client, attribs []Attrib, r io.Reader, destination string, extend string) (string, error) {
+	script, err := io.ReadAll(io.LimitReader(r, int64(MaxScriptSize)+1))
+	if err != nil {
+		return "", fmt.Errorf("pbs: reading job script: %w", err)
+	}
+	return SubmitBytes(c, attribs, script, destination, extend)
+}
+
+func submitTempFile(c Client, attribs []Attrib, script []byte, destination string, extend string) (string, error) {
+	f, err := os.CreateTemp("", "pbs-script-")
+	if err != nil {
+		return "", fmt.Errorf("pbs: writing job script: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.Write(script)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "", fmt.Errorf("pbs: writing job script: %w", err)
+	}
+	return c.Submit(attribs, f.Name(), destination, extend)
+}
@@ -0,0 +1,93 @@
+package pbs
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// pathClient hides the SubmitScript method of a Client, so that scripts
+// are submitted with a temporary file
+type pathClient struct {
+	Client
+	paths []string
+}
+
+func (c *pathClient) Submit(attribs []Attrib, script string, destination string, extend string) (string, error) {
+	c.paths = append(c.paths, script)
+	return c.Client.Submit(attribs, script, destination, extend)
+}
+
+func TestSubmitBytes(t *testing.T) {
+	server := NewFakeServer("fake")
+	client := connectFake(t, server)
+
+	body := "#!/bin/sh\necho hello\n"
+	id, err := SubmitBytes(client, nil, []byte(body), "", "")
+	if err != nil {
+		t.Fatalf("SubmitBytes failed: %s\n", err)
+	}
+	if script, _ := server.Script(id); string(script) != body {
+		t.Errorf("server has script %q\n", script)
+	}
+	if name := jobAttribute(t, client, id, ATTR_N); name != "STDIN" {
+		t.Errorf("job is called %s, expected STDIN\n", name)
+	}
+
+	id, err = SubmitReader(client, []Attrib{{Name: ATTR_N, Value: "named"}}, strings.NewReader(body), "", "")
+	if err != nil {
+		t.Fatalf("SubmitReader failed: %s\n", err)
+	}
+	if name := jobAttribute(t, client, id, ATTR_N); name != "named" {
+		t.Errorf("job is called %s, expected named\n", name)
+	}
+
+	if _, err := SubmitReader(client, nil, iotest.ErrReader(errors.New("broken")), "", ""); err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Errorf("SubmitReader with a broken reader returned %v\n", err)
+	}
+}
+
+func TestSubmitBytesTempFile(t *testing.T) {
+	server := NewFakeServer("fake")
+	client := &pathClient{Client: connectFake(t, server)}
+
+	body := "#!/bin/sh\necho hello\n"
+	id, err := SubmitBytes(client, nil, []byte(body), "", "")
+	if err != nil {
+		t.Fatalf("SubmitBytes failed: %s\n", err)
+	}
+	if script, _ := server.Script(id); string(script) != body {
+		t.Errorf("server has script %q\n", script)
+	}
+
+	_, err = SubmitBytes(client, nil, []byte(body), "missing", "")
+	if !errors.Is(err, ErrUnknownQueue) {
+		t.Errorf("SubmitBytes to a missing queue returned %v\n", err)
+	}
+	if len(client.paths) != 2 {
+		t.Fatalf("Submit was called %d times\n", len(client.paths))
+	}
+	for _, path := range client.paths {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("temporary script %s wasn't removed: %v\n", path, err)
+		}
+	}
+}
+
+func TestSubmitScriptTooLarge(t *testing.T) {
+	defer func(max int) { MaxScriptSize = max }(MaxScriptSize)
+	MaxScriptSize = 10
+
+	client := connectFake(t, NewFakeServer("fake"))
+	if _, err := SubmitBytes(client, nil, make([]byte, 11), "", ""); err != ErrScriptTooLarge {
+		t.Errorf("SubmitBytes of a large script returned %v\n", err)
+	}
+	if _, err := SubmitReader(client, nil, strings.NewReader(strings.Repeat("x", 100)), "", ""); err != ErrScriptTooLarge {
+		t.Errorf("SubmitReader of a large script returned %v\n", err)
+	}
+	if _, err := SubmitBytes(client, nil, make([]byte, 10), "", ""); err != nil {
+		t.Errorf("SubmitBytes of a script of MaxScriptSize failed: %s\n", err)
+	}
+}
@@ -0,0 +1,262 @@
+package pbs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// DefaultSweepConcurrency is the number of jobs a Sweep submits at once if
+// its Concurrency isn't set
+const DefaultSweepConcurrency = 4
+
+// Sweep submits a job for each point in a space of parameters, with the
+// job's script rendered from a template. For example, with
+//
+//	Params: map[string][]string{"alpha": {"0.1", "0.2"}, "seed": {"1", "2", "3"}}
+//
+// there are six jobs, and the template can use {{.alpha}} and {{.seed}}.
+type Sweep struct {
+	// Script is executed with the map of each point's parameters. A
+	// parameter missing from the map is an error, as with the
+	// "missingkey=error" option.
+	Script *template.Template
+	Params map[string][]string
+	// Zip makes the points from the i'th value of each parameter, which
+	// must all have the same number of values, rather than from every
+	// combination of values
+	Zip bool
+
+	// Attribs are given to every job. Name, Output and Error are templates
+	// for the Job_Name, Output_Path and Error_Path attributes, executed
+	// like Script, e.g. "fit-{{.alpha}}". Name defaults to "sweep-N", with
+	// N the point's index.
+	Attribs []Attrib
+	Name    string
+	Output  string
+	Error   string
+	// Variables adds the parameters to each job's Variable_List
+	Variables bool
+
+	Destination string
+	// Concurrency is the number of jobs submitted at once, by default
+	// DefaultSweepConcurrency
+	Concurrency int
+	// DryRun makes Submit render the jobs without submitting them
+	DryRun bool
+}
+
+// SweepJob is a job of a Sweep, and an entry of its manifest
+type SweepJob struct {
+	Params  map[string]string
+	Attribs []Attrib
+	Script  []byte
+	// JobID is set once the job is submitted, or Err if it couldn't be
+	JobID string
+	Err   error
+}
+
+// Points returns the parameters of each job of the sweep. Combinations are
+// in the order of the parameters' names, with the last name's values
+// changing fastest.
+func (s *Sweep) Points() ([]map[string]string, error) {
+	names := make([]string, 0, len(s.Params))
+	for name := range s.Params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		return nil, errors.New("pbs: sweep has no parameters")
+	}
+	for _, name := range names {
+		if len(s.Params[name]) == 0 {
+			return nil, fmt.Errorf("pbs: sweep parameter %q has no values", name)
+		}
+	}
+
+	if s.Zip {
+		n := len(s.Params[names[0]])
+		for _, name := range names {
+			if len(s.Params[name]) != n {
+				return nil, fmt.Errorf("pbs: sweep parameter %q has %d values, expected %d", name, len(s.Params[name]), n)
+			}
+		}
+		points := make([]map[string]string, n)
+		for i := range points {
+			points[i] = map[string]string{}
+			for _, name := range names {
+				points[i][name] = s.Params[name][i]
+			}
+		}
+		return points, nil
+	}
+
+	points := []map[string]string{{}}
+	for _, name := range names {
+		var next []map[string]string
+		for _, point := range points {
+			for _, value := range s.Params[name] {
+				p := make(map[string]string, len(point)+1)
+				for k, v := range point {
+					p[k] = v
+				}
+				p[name] = value
+				next = append(next, p)
+			}
+		}
+		points = next
+	}
+	return points, nil
+}
+
+// Render returns the jobs of the sweep with their scripts and attributes,
+// without submitting them
+func (s *Sweep) Render() ([]SweepJob, error) {
+	if s.Script == nil {
+		return nil, errors.New("pbs: sweep has no script template")
+	}
+	points, err := s.Points()
+	if err != nil {
+		return nil, err
+	}
+	script, err := s.Script.Clone()
+	if err != nil {
+		return nil, fmt.Errorf("pbs: sweep script template: %w", err)
+	}
+	script.Option("missingkey=error")
+
+	attribTemplates := []struct {
+		name string
+		text string
+	}{
+		{ATTR_N, s.Name},
+		{ATTR_o, s.Output},
+		{ATTR_e, s.Error},
+	}
+	templates := map[string]*template.Template{}
+	for _, a := range attribTemplates {
+		if a.text == "" {
+			continue
+		}
+		t, err := template.New(a.name).Option("missingkey=error").Parse(a.text)
+		if err != nil {
+			return nil, fmt.Errorf("pbs: sweep %s template: %w", a.name, err)
+		}
+		templates[a.name] = t
+	}
+
+	jobs := make([]SweepJob, len(points))
+	for i, point := range points {
+		job := SweepJob{Params: point}
+
+		var rendered bytes.Buffer
+		if err := script.Execute(&rendered, point); err != nil {
+			return nil, fmt.Errorf("pbs: rendering sweep job %d: %w", i, err)
+		}
+		job.Script = rendered.Bytes()
+
+		job.Attribs = append([]Attrib(nil), s.Attribs...)
+		if s.Name == "" && attribValue(job.Attribs, ATTR_N, "") == nil {
+			job.Attribs = append(job.Attribs, Attrib{Name: ATTR_N, Value: "sweep-" + strconv.Itoa(i)})
+		}
+		for _, a := range attribTemplates {
+			t, ok := templates[a.name]
+			if !ok {
+				continue
+			}
+			var value strings.Builder
+			if err := t.Execute(&value, point); err != nil {
+				return nil, fmt.Errorf("pbs: rendering sweep job %d: %w", i, err)
+			}
+			job.Attribs = setAttribs(job.Attribs, []Attrib{{Name: a.name, Value: value.String()}}, false)
+		}
+		if s.Variables {
+			if job.Attribs, err = addVariables(job.Attribs, point); err != nil {
+				return nil, err
+			}
+		}
+		jobs[i] = job
+	}
+	return jobs, nil
+}
+
+// addVariables adds vars to the Variable_List in attribs
+func addVariables(attribs []Attrib, vars map[string]string) ([]Attrib, error) {
+	env := Env{}
+	if a := attribValue(attribs, ATTR_v, ""); a != nil {
+		var err error
+		if env, err = ParseEnv(a.Value); err != nil {
+			return nil, err
+		}
+	}
+	env = env.Merge(vars)
+	return setAttribs(attribs, []Attrib{env.Attrib()}, false), nil
+}
+
+// Submit renders the jobs of the sweep and submits them to server with
+// connections from pool, Concurrency at a time, unless DryRun is set. It
+// returns the manifest of the jobs, with the JobID, or Err, of each. If any
+// job couldn't be submitted the error of the first is also returned; the
+// jobs which haven't been submitted when ctx is done fail with ctx.Err().
+func (s *Sweep) Submit(ctx context.Context, pool *Pool, server string) ([]SweepJob, error) {
+	jobs, err := s.Render()
+	if err != nil || s.DryRun {
+		return jobs, err
+	}
+
+	workers := s.Concurrency
+	if workers <= 0 {
+		workers = DefaultSweepConcurrency
+	}
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				// Not pool.Do, which would submit the job again after a
+				// connection error
+				job := &jobs[i]
+				conn, err := pool.Get(ctx, server)
+				if err != nil {
+					job.Err = err
+					continue
+				}
+				job.JobID, job.Err = conn.SubmitScript(job.Attribs, job.Script, s.Destination, "")
+				pool.Put(conn, job.Err)
+			}
+		}()
+	}
+
+	for i := range jobs {
+		if ctx.Err() != nil {
+			jobs[i].Err = ctx.Err()
+			continue
+		}
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	failed := 0
+	var first error
+	for i, job := range jobs {
+		if job.Err != nil {
+			if failed == 0 {
+				first = fmt.Errorf("pbs: submitting sweep job %d: %w", i, job.Err)
+			}
+			failed++
+		}
+	}
+	if failed > 1 {
+		first = fmt.Errorf("%w (and %d more jobs)", first, failed-1)
+	}
+	return jobs, first
+}
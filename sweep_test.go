@@ -0,0 +1,120 @@
+package pbs
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func TestSweepPoints(t *testing.T) {
+	s := &Sweep{Params: map[string][]string{"b": {"1", "2"}, "a": {"x", "y", "z"}}}
+	points, err := s.Points()
+	if err != nil || len(points) != 6 {
+		t.Fatalf("Points returned %v, %v\n", points, err)
+	}
+	if !reflect.DeepEqual(points[0], map[string]string{"a": "x", "b": "1"}) || !reflect.DeepEqual(points[1], map[string]string{"a": "x", "b": "2"}) {
+		t.Errorf("Points returned %v\n", points)
+	}
+
+	if _, err := s.Render(); err == nil {
+		t.Errorf("Render without a script didn't fail\n")
+	}
+	s.Zip = true
+	if _, err := s.Points(); err == nil {
+		t.Errorf("Points of zipped lists of different lengths didn't fail\n")
+	}
+	s.Params["b"] = append(s.Params["b"], "3")
+	points, err = s.Points()
+	if err != nil || !reflect.DeepEqual(points[2], map[string]string{"a": "z", "b": "3"}) {
+		t.Errorf("zipped Points returned %v, %v\n", points, err)
+	}
+	if _, err := (&Sweep{}).Points(); err == nil {
+		t.Errorf("Points without parameters didn't fail\n")
+	}
+	if _, err := (&Sweep{Params: map[string][]string{"a": {"x"}, "b": nil}}).Points(); err == nil {
+		t.Errorf("Points with a parameter without values didn't fail\n")
+	}
+}
+
+func TestSweepRender(t *testing.T) {
+	s := &Sweep{
+		Script:    template.Must(template.New("fit").Parse("#!/bin/sh\nfit --alpha {{.alpha}} --seed {{.seed}}\n")),
+		Params:    map[string][]string{"alpha": {"0.1", "0.2"}, "seed": {"1", "2"}},
+		Attribs:   []Attrib{{Name: ATTR_l, Resource: "walltime", Value: "01:00:00"}, {Name: ATTR_v, Value: "MODE=fast"}},
+		Name:      "fit-{{.alpha}}-{{.seed}}",
+		Output:    "/data/fit-{{.alpha}}-{{.seed}}.out",
+		Variables: true,
+		DryRun:    true,
+	}
+	jobs, err := s.Submit(context.Background(), nil, "")
+	if err != nil || len(jobs) != 4 {
+		t.Fatalf("dry run returned %d jobs, %v\n", len(jobs), err)
+	}
+	job := jobs[3]
+	if string(job.Script) != "#!/bin/sh\nfit --alpha 0.2 --seed 2\n" || job.JobID != "" {
+		t.Errorf("job 3 is %+v\n", job)
+	}
+	expected := []Attrib{
+		{Name: ATTR_l, Resource: "walltime", Value: "01:00:00"},
+		{Name: ATTR_N, Value: "fit-0.2-2"},
+		{Name: ATTR_o, Value: "/data/fit-0.2-2.out"},
+		{Name: ATTR_v, Value: "MODE=fast,alpha=0.2,seed=2"},
+	}
+	if !reflect.DeepEqual(job.Attribs, expected) {
+		t.Errorf("job 3 has attributes %+v\n", job.Attribs)
+	}
+
+	s.Name = "{{.missing}}"
+	if _, err := s.Render(); err == nil {
+		t.Errorf("Render with a missing parameter didn't fail\n")
+	}
+	s.Name = ""
+	s.Script = template.Must(template.New("typo").Parse("fit --alpha {{.aplha}}\n"))
+	if _, err := s.Render(); err == nil {
+		t.Errorf("Render of a script with a missing parameter didn't fail\n")
+	}
+}
+
+func TestSweepSubmit(t *testing.T) {
+	server := NewFakeServer("fake")
+	pool := &Pool{Backend: server}
+	defer pool.Close()
+
+	s := &Sweep{
+		Script:      template.Must(template.New("job").Parse("echo {{.n}}\n")),
+		Params:      map[string][]string{"n": {"1", "2", "3", "4", "5", "6", "7", "8"}},
+		Concurrency: 3,
+	}
+	jobs, err := s.Submit(context.Background(), pool, "")
+	if err != nil {
+		t.Fatalf("Submit failed: %s\n", err)
+	}
+	seen := map[string]bool{}
+	for i, job := range jobs {
+		script, err := server.Script(job.JobID)
+		if err != nil || string(script) != "echo "+job.Params["n"]+"\n" || seen[job.JobID] {
+			t.Errorf("job %d, %s, has script %q, %v\n", i, job.JobID, script, err)
+		}
+		seen[job.JobID] = true
+		if name := jobAttribute(t, connectFake(t, server), job.JobID, ATTR_N); name != "sweep-"+string(rune('0'+i)) {
+			t.Errorf("job %d is called %s\n", i, name)
+		}
+	}
+
+	s.Destination = "missing"
+	jobs, err = s.Submit(context.Background(), pool, "")
+	if !errors.Is(err, ErrUnknownQueue) || !strings.Contains(err.Error(), "and 7 more jobs") || jobs[5].JobID != "" {
+		t.Errorf("Submit to a missing queue returned %v\n", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s.Destination = ""
+	jobs, err = s.Submit(ctx, pool, "")
+	if !errors.Is(err, context.Canceled) || !errors.Is(jobs[7].Err, context.Canceled) {
+		t.Errorf("Submit with a cancelled context returned %v\n", err)
+	}
+}
@@ -0,0 +1,92 @@
+//go:build cgo && !nolibtorque
+
+package pbs
+
+// DefaultBackend is the Backend used by Connect
+var DefaultBackend Backend = Torque{}
+
+// Torque is the Backend which talks to the server through libtorque
+type Torque struct{}
+
+// DefaultServer returns the server used when Connect is given an empty
+// server name
+func (Torque) DefaultServer() string {
+	return Pbs_default()
+}
+
+// Connect calls Pbs_connect and wraps the handle in a *TorqueClient
+func (Torque) Connect(server string) (Client, error) {
+	handle, err := Pbs_connect(server)
+	if err != nil {
+		return nil, err
+	}
+	return &TorqueClient{Handle: handle}, nil
+}
+
+// TorqueClient is a Client using a libtorque connection handle. Handle can
+// still be passed to the Pbs_* functions which have no Client equivalent.
+type TorqueClient struct {
+	Handle int
+}
+
+var _ Client = (*TorqueClient)(nil)
+
+func (c *TorqueClient) Submit(attribs []Attrib, script string, destination string, extend string) (string, error) {
+	return Pbs_submit(c.Handle, attribs, script, destination, extend)
+}
+
+func (c *TorqueClient) StatJob(id string, attribs []Attrib, extend string) ([]BatchStatus, error) {
+	return Pbs_statjob(c.Handle, id, attribs, extend)
+}
+
+func (c *TorqueClient) StatNode(id string, attribs []Attrib, extend string) ([]BatchStatus, error) {
+	return Pbs_statnode(c.Handle, id, attribs, extend)
+}
+
+func (c *TorqueClient) StatQue(id string, attribs []Attrib, extend string) ([]BatchStatus, error) {
+	return Pbs_statque(c.Handle, id, attribs, extend)
+}
+
+func (c *TorqueClient) StatServer(attribs []Attrib, extend string) ([]BatchStatus, error) {
+	return Pbs_statserver(c.Handle, attribs, extend)
+}
+
+func (c *TorqueClient) SelectJob(attribs []Attrib, extend string) ([]string, error) {
+	return Pbs_selectjob(c.Handle, attribs, extend)
+}
+
+func (c *TorqueClient) SelStat(attribs []Attrib, extend string) ([]BatchStatus, error) {
+	return Pbs_selstat(c.Handle, attribs, extend)
+}
+
+func (c *TorqueClient) HoldJob(id string, holdType Hold, extend string) error {
+	return Pbs_holdjob(c.Handle, id, holdType, extend)
+}
+
+func (c *TorqueClient) RlsJob(id string, holdType Hold, extend string) error {
+	return Pbs_rlsjob(c.Handle, id, holdType, extend)
+}
+
+func (c *TorqueClient) DelJob(id string, extend string) error {
+	return Pbs_deljob(c.Handle, id, extend)
+}
+
+func (c *TorqueClient) AlterJob(id string, attribs []Attrib, extend string) error {
+	return Pbs_alterjob(c.Handle, id, attribs, extend)
+}
+
+func (c *TorqueClient) MoveJob(id string, destination string, extend string) error {
+	return Pbs_movejob(c.Handle, id, destination, extend)
+}
+
+func (c *TorqueClient) SigJob(id string, signal string, extend string) error {
+	return Pbs_sigjob(c.Handle, id, signal, extend)
+}
+
+func (c *TorqueClient) Manager(command Command, objType ObjectType, name string, attribs []Attrib, extend string) error {
+	return Pbs_manager(c.Handle, command, objType, name, attribs, extend)
+}
+
+func (c *TorqueClient) Disconnect() error {
+	return Pbs_disconnect(c.Handle)
+}
@@ -0,0 +1,146 @@
+package pbs
+
+// BatchStatus represents the batch_status structure
+type BatchStatus struct {
+	Name       string
+	Text       string
+	Attributes []Attrib
+}
+
+// Attrib represents the attrl and attropl structures
+type Attrib struct {
+	Name     string
+	Resource string
+	Value    string
+	Op       Operator
+}
+
+// Manner defines how the server should be terminated
+type Manner int
+
+// Hold defines the type of job hold to place on a job
+type Hold string
+
+// MessageStream which output stream should be written to
+type MessageStream int
+
+// Operator defines types of logical comparator
+type Operator int
+
+type Command int
+
+type ObjectType int
+
+// The values of these constants are those in torque/pbs_ifl.h, they are
+// checked against the headers when the package is built with libtorque.
+const (
+	SHUT_IMMEDIATE                 Manner        = 0
+	SHUT_DELAY                     Manner        = 1
+	USER_HOLD                      Hold          = "u"
+	OTHER_HOLD                     Hold          = "o"
+	SYSTEM_HOLD                    Hold          = "s"
+	MSG_ERR                        MessageStream = 2
+	MSG_OUT                        MessageStream = 1
+	MGR_CMD_CREATE                 Command       = 0
+	MGR_CMD_DELETE                 Command       = 1
+	MGR_CMD_SET                    Command       = 2
+	MGR_CMD_UNSET                  Command       = 3
+	MGR_CMD_LIST                   Command       = 4
+	MGR_CMD_PRINT                  Command       = 5
+	MGR_CMD_ACTIVE                 Command       = 6
+	MGR_OBJ_NONE                   ObjectType    = -1
+	MGR_OBJ_SERVER                 ObjectType    = 0
+	MGR_OBJ_QUEUE                  ObjectType    = 1
+	MGR_OBJ_JOB                    ObjectType    = 2
+	MGR_OBJ_NODE                   ObjectType    = 3
+	ATTR_a                         string        = "Execution_Time"
+	ATTR_c                         string        = "Checkpoint"
+	ATTR_e                         string        = "Error_Path"
+	ATTR_f                         string        = "fault_tolerant"
+	ATTR_g                         string        = "group_list"
+	ATTR_h                         string        = "Hold_Types"
+	ATTR_j                         string        = "Join_Path"
+	ATTR_k                         string        = "Keep_Files"
+	ATTR_l                         string        = "Resource_List"
+	ATTR_m                         string        = "Mail_Points"
+	ATTR_o                         string        = "Output_Path"
+	ATTR_p                         string        = "Priority"
+	ATTR_q                         string        = "destination"
+	ATTR_r                         string        = "Rerunable"
+	ATTR_t                         string        = "job_array_request"
+	ATTR_array_id                  string        = "job_array_id"
+	ATTR_u                         string        = "User_List"
+	ATTR_v                         string        = "Variable_List"
+	ATTR_A                         string        = "Account_Name"
+	ATTR_args                      string        = "job_arguments"
+	ATTR_M                         string        = "Mail_Users"
+	ATTR_N                         string        = "Job_Name"
+	ATTR_S                         string        = "Shell_Path_List"
+	ATTR_depend                    string        = "depend"
+	ATTR_inter                     string        = "interactive"
+	ATTR_stagein                   string        = "stagein"
+	ATTR_stageout                  string        = "stageout"
+	ATTR_jobtype                   string        = "jobtype"
+	ATTR_submit_host               string        = "submit_host"
+	ATTR_init_work_dir             string        = "init_work_dir"
+	ATTR_ctime                     string        = "ctime"
+	ATTR_exechost                  string        = "exec_host"
+	ATTR_execport                  string        = "exec_port"
+	ATTR_mtime                     string        = "mtime"
+	ATTR_qtime                     string        = "qtime"
+	ATTR_session                   string        = "session_id"
+	ATTR_euser                     string        = "euser"
+	ATTR_egroup                    string        = "egroup"
+	ATTR_hashname                  string        = "hashname"
+	ATTR_hopcount                  string        = "hop_count"
+	ATTR_security                  string        = "security"
+	ATTR_sched_hint                string        = "sched_hint"
+	ATTR_substate                  string        = "substate"
+	ATTR_name                      string        = "Job_Name"
+	ATTR_owner                     string        = "Job_Owner"
+	ATTR_used                      string        = "resources_used"
+	ATTR_state                     string        = "job_state"
+	ATTR_queue                     string        = "queue"
+	ATTR_server                    string        = "server"
+	ATTR_maxrun                    string        = "max_running"
+	ATTR_maxreport                 string        = "max_report"
+	ATTR_total                     string        = "total_jobs"
+	ATTR_comment                   string        = "comment"
+	ATTR_cookie                    string        = "cookie"
+	ATTR_qrank                     string        = "queue_rank"
+	ATTR_altid                     string        = "alt_id"
+	ATTR_etime                     string        = "etime"
+	ATTR_exitstat                  string        = "exit_status"
+	ATTR_forwardx11                string        = "forward_x11"
+	ATTR_submit_args               string        = "submit_args"
+	ATTR_tokens                    string        = "tokens"
+	ATTR_netcounter                string        = "net_counter"
+	ATTR_umask                     string        = "umask"
+	ATTR_start_time                string        = "start_time"
+	ATTR_start_count               string        = "start_count"
+	ATTR_checkpoint_dir            string        = "checkpoint_dir"
+	ATTR_checkpoint_name           string        = "checkpoint_name"
+	ATTR_checkpoint_time           string        = "checkpoint_time"
+	ATTR_checkpoint_restart_status string        = "checkpoint_restart_status"
+	ATTR_restart_name              string        = "restart_name"
+	ATTR_comp_time                 string        = "comp_time"
+	ATTR_reported                  string        = "reported"
+	ATTR_intcmd                    string        = "inter_cmd"
+	ATTR_P                         string        = "proxy_user"
+	ATTR_node_exclusive            string        = "node_exclusive"
+	ATTR_exec_gpus                 string        = "exec_gpus"
+	ATTR_J                         string        = "job_id"
+	SET                            Operator      = 0
+	UNSET                          Operator      = 1
+	INCR                           Operator      = 2
+	DECR                           Operator      = 3
+	EQ                             Operator      = 4
+	NE                             Operator      = 5
+	GE                             Operator      = 6
+	GT                             Operator      = 7
+	LE                             Operator      = 8
+	LT                             Operator      = 9
+	DFLT                           Operator      = 10
+	MERGE                          Operator      = 11
+	INCR_OLD                       Operator      = 12
+)
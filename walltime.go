@@ -0,0 +1,113 @@
+package pbs
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Walltime is a time resource, such as walltime or cput, which TORQUE
+// writes as HH:MM:SS.
+type Walltime time.Duration
+
+// ParseWalltime parses a walltime in the formats TORQUE accepts: HH:MM:SS,
+// MM:SS or plain seconds.
+func ParseWalltime(s string) (Walltime, error) {
+	d, err := parseDuration(s)
+	if err != nil {
+		return 0, errors.New("pbs: " + err.Error())
+	}
+	return Walltime(d), nil
+}
+
+// Duration returns w as a time.Duration
+func (w Walltime) Duration() time.Duration {
+	return time.Duration(w)
+}
+
+// String formats w as HH:MM:SS, truncated to the second
+func (w Walltime) String() string {
+	return hms(time.Duration(w))
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (w Walltime) MarshalText() ([]byte, error) {
+	return []byte(w.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (w *Walltime) UnmarshalText(b []byte) error {
+	v, err := ParseWalltime(string(b))
+	if err != nil {
+		return err
+	}
+	*w = v
+	return nil
+}
+
+func hms(d time.Duration) string {
+	d = d.Truncate(time.Second)
+	return fmt.Sprintf("%02d:%02d:%02d", int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60)
+}
+
+// parseDuration parses a time in walltime format, [[HH:]MM:]SS, where the
+// seconds can have a fraction
+func parseDuration(s string) (time.Duration, error) {
+	invalid := errors.New("invalid duration " + strconv.Quote(s))
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	if len(parts) > 3 {
+		return 0, invalid
+	}
+
+	secs, frac, hasFrac := strings.Cut(parts[len(parts)-1], ".")
+	if hasFrac && !isDigits(frac) {
+		return 0, invalid
+	}
+	parts[len(parts)-1] = secs
+
+	// Sum the seconds, keeping within what a time.Duration can hold
+	const maxSeconds = uint64(math.MaxInt64 / int64(time.Second))
+	var total uint64
+	unit := uint64(1)
+	for i := len(parts) - 1; i >= 0; i-- {
+		if !isDigits(parts[i]) {
+			return 0, invalid
+		}
+		n, err := strconv.ParseUint(parts[i], 10, 64)
+		if err != nil || n > (maxSeconds-total)/unit {
+			return 0, errors.New("duration " + strconv.Quote(s) + " is too long")
+		}
+		total += n * unit
+		if unit == 1 {
+			unit = 60
+		} else {
+			unit = 3600
+		}
+	}
+
+	// The fraction is truncated to nanoseconds
+	var nanos int64
+	if hasFrac {
+		nanos, _ = strconv.ParseInt((frac + "000000000")[:9], 10, 64)
+	}
+	if total == maxSeconds && nanos > math.MaxInt64%int64(time.Second) {
+		return 0, errors.New("duration " + strconv.Quote(s) + " is too long")
+	}
+	return time.Duration(total)*time.Second + time.Duration(nanos), nil
+}
+
+// isDigits reports whether s is a non-empty string of decimal digits
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
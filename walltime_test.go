@@ -0,0 +1,49 @@
+package pbs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWalltime(t *testing.T) {
+	tests := []struct {
+		s   string
+		d   time.Duration
+		str string
+	}{
+		{"01:00:00", time.Hour, "01:00:00"},
+		{"3600", time.Hour, "01:00:00"},
+		{"90", 90 * time.Second, "00:01:30"},
+		{"10:30", 10*time.Minute + 30*time.Second, "00:10:30"},
+		{"120:00:01", 120*time.Hour + time.Second, "120:00:01"},
+		{"00:00:01.5", 1500 * time.Millisecond, "00:00:01"},
+		{"0.0000000019", time.Nanosecond, "00:00:00"},
+		{"2562047:47:16", 2562047*time.Hour + 47*time.Minute + 16*time.Second, "2562047:47:16"},
+	}
+	for _, test := range tests {
+		w, err := ParseWalltime(test.s)
+		if err != nil || w.Duration() != test.d {
+			t.Errorf("ParseWalltime(%q) returned %s, %v\n", test.s, w.Duration(), err)
+		}
+		if w.String() != test.str {
+			t.Errorf("%s formatted as %q, expected %q\n", w.Duration(), w.String(), test.str)
+		}
+	}
+	for _, bad := range []string{"", "1:2:3:4", "a:00:00", "-5", "01:-1:00", "NaN", "Inf", "1e3", "+5", "1.", ".5", "1.5:00", "99999999:00:00", "2562047:47:17", "18446744073709551616"} {
+		if _, err := ParseWalltime(bad); err == nil {
+			t.Errorf("ParseWalltime(%q) didn't fail\n", bad)
+		}
+	}
+
+	var job struct {
+		Walltime Walltime `pbs:"Resource_List,walltime"`
+		Used     Walltime `pbs:"resources_used,walltime"`
+	}
+	err := Unmarshal(BatchStatus{Attributes: []Attrib{
+		{Name: "Resource_List", Resource: "walltime", Value: "02:00:00"},
+		{Name: "resources_used", Resource: "walltime", Value: "00:15:07"},
+	}}, &job)
+	if err != nil || job.Walltime.Duration() != 2*time.Hour || job.Used.Duration() != 15*time.Minute+7*time.Second {
+		t.Errorf("Unmarshal of walltimes returned %+v, %v\n", job, err)
+	}
+}
@@ -0,0 +1,318 @@
+package pbs
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ErrCycle is returned, wrapped, for a Workflow whose steps depend on each
+// other in a cycle
+var ErrCycle = errors.New("pbs: workflow has a dependency cycle")
+
+// Step is a job in a Workflow
+type Step struct {
+	Name        string
+	Script      string
+	Attribs     []Attrib
+	Destination string
+
+	// After are the names of the steps this one depends on
+	After []string
+	// Depend is the type of the dependency on After, DependAfterOK if
+	// empty. Only the after types, which make the step wait for the steps
+	// it depends on, can be used.
+	Depend DependType
+
+	// JobID is set when the step is submitted
+	JobID string
+}
+
+// Workflow is a graph of jobs, each of which waits for the jobs it depends
+// on, which are submitted together
+type Workflow struct {
+	steps map[string]*Step
+	order []string
+}
+
+// NewWorkflow returns an empty Workflow
+func NewWorkflow() *Workflow {
+	return &Workflow{steps: map[string]*Step{}}
+}
+
+// workflowDependTypes are the dependency types a Step can have on the
+// steps it depends on: the before types would make the parent wait for the
+// child, and on and synccount don't take jobs
+var workflowDependTypes = map[DependType]bool{
+	"":                    true,
+	DependAfter:           true,
+	DependAfterOK:         true,
+	DependAfterNotOK:      true,
+	DependAfterAny:        true,
+	DependAfterStartArray: true,
+	DependAfterOKArray:    true,
+	DependAfterNotOKArray: true,
+	DependAfterAnyArray:   true,
+}
+
+// Add adds a step to w. Its name must be unique, and the steps it depends
+// on can be added later.
+func (w *Workflow) Add(step Step) error {
+	if step.Name == "" {
+		return errors.New("pbs: workflow step has no name")
+	}
+	if _, ok := w.steps[step.Name]; ok {
+		return errors.New("pbs: duplicate workflow step " + strconv.Quote(step.Name))
+	}
+	if !workflowDependTypes[step.Depend] {
+		return fmt.Errorf("pbs: workflow step %q can't depend on other steps with %q", step.Name, step.Depend)
+	}
+	step.After = append([]string(nil), step.After...)
+	w.steps[step.Name] = &step
+	w.order = append(w.order, step.Name)
+	return nil
+}
+
+// Depend makes the step child depend on parents
+func (w *Workflow) Depend(child string, parents ...string) error {
+	step, ok := w.steps[child]
+	if !ok {
+		return errors.New("pbs: unknown workflow step " + strconv.Quote(child))
+	}
+	step.After = append(step.After, parents...)
+	return nil
+}
+
+// Step returns the step called name, or nil if there isn't one
+func (w *Workflow) Step(name string) *Step {
+	return w.steps[name]
+}
+
+// Order returns the names of the steps in the order in which they're
+// submitted, each after the steps it depends on and otherwise in the order
+// they were added. It returns an error wrapping ErrCycle if there is no
+// such order.
+func (w *Workflow) Order() ([]string, error) {
+	children := map[string][]string{}
+	waiting := map[string]int{}
+	for _, name := range w.order {
+		for _, parent := range w.steps[name].After {
+			if _, ok := w.steps[parent]; !ok {
+				return nil, fmt.Errorf("pbs: workflow step %q depends on unknown step %q", name, parent)
+			}
+			children[parent] = append(children[parent], name)
+			waiting[name]++
+		}
+	}
+
+	var order, ready []string
+	for _, name := range w.order {
+		if waiting[name] == 0 {
+			ready = append(ready, name)
+		}
+	}
+	for len(ready) > 0 {
+		name := ready[0]
+		ready = ready[1:]
+		order = append(order, name)
+		for _, child := range children[name] {
+			if waiting[child]--; waiting[child] == 0 {
+				ready = append(ready, child)
+			}
+		}
+	}
+
+	if len(order) < len(w.order) {
+		return nil, fmt.Errorf("%w: %s", ErrCycle, strings.Join(w.cycle(waiting), " -> "))
+	}
+	return order, nil
+}
+
+// cycle finds a cycle among the steps still waiting after a topological
+// sort, by following parents until a step is seen twice. The cycle is
+// returned with each step before the one which depends on it.
+func (w *Workflow) cycle(waiting map[string]int) []string {
+	var name string
+	for _, n := range w.order {
+		if waiting[n] > 0 {
+			name = n
+			break
+		}
+	}
+
+	seen := map[string]int{}
+	var path []string
+	for {
+		if i, ok := seen[name]; ok {
+			path = append(path[i:], name)
+			for l, r := 0, len(path)-1; l < r; l, r = l+1, r-1 {
+				path[l], path[r] = path[r], path[l]
+			}
+			return path
+		}
+		seen[name] = len(path)
+		path = append(path, name)
+		for _, parent := range w.steps[name].After {
+			if waiting[parent] > 0 {
+				name = parent
+				break
+			}
+		}
+	}
+}
+
+// WorkflowError is returned when a Workflow's submission fails
+type WorkflowError struct {
+	// Step is the step which couldn't be submitted, or released
+	Step string
+	Err  error
+	// Rollback are the errors from deleting the jobs already submitted,
+	// and Undeleted the IDs of the jobs which couldn't be deleted, whose
+	// steps keep their JobID
+	Rollback  []error
+	Undeleted []string
+}
+
+func (e *WorkflowError) Error() string {
+	msg := "pbs: submitting workflow step " + strconv.Quote(e.Step) + ": " + e.Err.Error()
+	if len(e.Rollback) > 0 {
+		msg += fmt.Sprintf(" (jobs %s not deleted: %s)", strings.Join(e.Undeleted, ", "), e.Rollback[0])
+	}
+	return msg
+}
+
+func (e *WorkflowError) Unwrap() error {
+	return e.Err
+}
+
+// Submit submits the steps in Order, setting each step's JobID and adding
+// the IDs of the steps it depends on to its depend attribute. The steps
+// which don't depend on others are held until all of the steps have been
+// submitted, so that none finish before the steps which depend on them
+// are submitted.
+//
+// If a step can't be submitted, the jobs already submitted are deleted and
+// a *WorkflowError is returned.
+func (w *Workflow) Submit(c Client) error {
+	order, err := w.Order()
+	if err != nil {
+		return err
+	}
+
+	var submitted, held []*Step
+	fail := func(step string, err error) error {
+		e := &WorkflowError{Step: step, Err: err}
+		for i := len(submitted) - 1; i >= 0; i-- {
+			if err := c.DelJob(submitted[i].JobID, ""); err != nil {
+				e.Rollback = append(e.Rollback, err)
+				e.Undeleted = append(e.Undeleted, submitted[i].JobID)
+				continue
+			}
+			submitted[i].JobID = ""
+		}
+		return e
+	}
+
+	for _, name := range order {
+		step := w.steps[name]
+		attribs, hold, err := step.attribs(w)
+		if err != nil {
+			return fail(name, err)
+		}
+		id, err := c.Submit(attribs, step.Script, step.Destination, "")
+		if err != nil {
+			return fail(name, err)
+		}
+		step.JobID = id
+		submitted = append(submitted, step)
+		if hold {
+			held = append(held, step)
+		}
+	}
+
+	for _, step := range held {
+		if err := c.RlsJob(step.JobID, USER_HOLD, ""); err != nil {
+			return fail(step.Name, err)
+		}
+	}
+	return nil
+}
+
+// attribs returns the attributes to submit the step with, and whether a
+// user hold has been added to them
+func (s *Step) attribs(w *Workflow) ([]Attrib, bool, error) {
+	attribs := make([]Attrib, 0, len(s.Attribs)+1)
+	var deps Dependencies
+	hold := -1
+	for _, a := range s.Attribs {
+		switch a.Name {
+		case ATTR_depend:
+			var err error
+			if deps, err = ParseDependencies(a.Value); err != nil {
+				return nil, false, err
+			}
+			continue
+		case ATTR_h:
+			hold = len(attribs)
+		}
+		attribs = append(attribs, a)
+	}
+
+	if len(s.After) == 0 {
+		if len(deps) > 0 {
+			attribs = append(attribs, deps.Attrib())
+		}
+		if hold < 0 {
+			return append(attribs, Attrib{Name: ATTR_h, Value: string(USER_HOLD)}), true, nil
+		}
+		if holds := strings.Trim(attribs[hold].Value, "n"); !strings.Contains(holds, string(USER_HOLD)) {
+			attribs[hold].Value = holds + string(USER_HOLD)
+			return attribs, true, nil
+		}
+		return attribs, false, nil
+	}
+
+	depend := s.Depend
+	if depend == "" {
+		depend = DependAfterOK
+	}
+	for _, parent := range s.After {
+		deps = deps.Add(depend, w.steps[parent].JobID)
+	}
+	return append(attribs, deps.Attrib()), false, nil
+}
+
+// JobIDs returns the job ID of each submitted step, by name
+func (w *Workflow) JobIDs() map[string]string {
+	ids := map[string]string{}
+	for name, step := range w.steps {
+		if step.JobID != "" {
+			ids[name] = step.JobID
+		}
+	}
+	return ids
+}
+
+// WriteDOT writes the workflow as a Graphviz digraph, with the job IDs of
+// submitted steps in their labels
+func (w *Workflow) WriteDOT(out io.Writer) error {
+	b := bufio.NewWriter(out)
+	b.WriteString("digraph workflow {\n")
+	for _, name := range w.order {
+		label := name
+		if id := w.steps[name].JobID; id != "" {
+			label += "\n" + id
+		}
+		fmt.Fprintf(b, "\t%s [label=%s];\n", strconv.Quote(name), strconv.Quote(label))
+	}
+	for _, name := range w.order {
+		for _, parent := range w.steps[name].After {
+			fmt.Fprintf(b, "\t%s -> %s;\n", strconv.Quote(parent), strconv.Quote(name))
+		}
+	}
+	b.WriteString("}\n")
+	return b.Flush()
+}
@@ -0,0 +1,153 @@
+package pbs
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func pipeline(t *testing.T) *Workflow {
+	w := NewWorkflow()
+	for _, step := range []Step{
+		{Name: "merge", Script: "merge.sh", After: []string{"left", "right"}},
+		{Name: "prep", Script: "prep.sh"},
+		{Name: "left", Script: "left.sh", After: []string{"prep"}},
+		{Name: "right", Script: "right.sh", After: []string{"prep"}, Depend: DependAfterAny},
+	} {
+		if err := w.Add(step); err != nil {
+			t.Fatalf("Add(%s) failed: %s\n", step.Name, err)
+		}
+	}
+	return w
+}
+
+func TestWorkflowOrder(t *testing.T) {
+	w := pipeline(t)
+	order, err := w.Order()
+	if err != nil || !reflect.DeepEqual(order, []string{"prep", "left", "right", "merge"}) {
+		t.Errorf("Order returned %v, %v\n", order, err)
+	}
+
+	if err := w.Add(Step{Name: "prep"}); err == nil {
+		t.Errorf("adding a duplicate step didn't fail\n")
+	}
+	if err := w.Depend("missing", "prep"); err == nil {
+		t.Errorf("Depend of a missing step didn't fail\n")
+	}
+
+	w.Depend("prep", "merge")
+	_, err = w.Order()
+	if !errors.Is(err, ErrCycle) || !strings.Contains(err.Error(), "merge -> prep -> left -> merge") {
+		t.Errorf("Order of a cycle returned %v\n", err)
+	}
+
+	w = NewWorkflow()
+	w.Add(Step{Name: "a", After: []string{"b"}})
+	if _, err := w.Order(); err == nil || errors.Is(err, ErrCycle) {
+		t.Errorf("Order with an unknown step returned %v\n", err)
+	}
+}
+
+func TestWorkflowSubmit(t *testing.T) {
+	server := NewFakeServer("fake")
+	client := connectFake(t, server)
+
+	w := pipeline(t)
+	if err := w.Submit(client); err != nil {
+		t.Fatalf("Submit failed: %s\n", err)
+	}
+	ids := w.JobIDs()
+	expected := map[string]string{"prep": "1.fake", "left": "2.fake", "right": "3.fake", "merge": "4.fake"}
+	if !reflect.DeepEqual(ids, expected) {
+		t.Errorf("JobIDs returned %v\n", ids)
+	}
+
+	if v := jobAttribute(t, client, ids["merge"], ATTR_depend); v != "afterok:2.fake:3.fake" {
+		t.Errorf("merge has depend %q\n", v)
+	}
+	if v := jobAttribute(t, client, ids["right"], ATTR_depend); v != "afterany:1.fake" {
+		t.Errorf("right has depend %q\n", v)
+	}
+	if v := jobAttribute(t, client, ids["prep"], ATTR_h); v != "n" {
+		t.Errorf("prep is still held with %q\n", v)
+	}
+
+	var dot bytes.Buffer
+	if err := w.WriteDOT(&dot); err != nil {
+		t.Fatalf("WriteDOT failed: %s\n", err)
+	}
+	for _, line := range []string{`"prep" [label="prep\n1.fake"];`, `"left" -> "merge";`, `"prep" -> "right";`} {
+		if !strings.Contains(dot.String(), line) {
+			t.Errorf("WriteDOT output doesn't contain %s:\n%s", line, dot.String())
+		}
+	}
+}
+
+func TestWorkflowRollback(t *testing.T) {
+	server := NewFakeServer("fake")
+	client := connectFake(t, server)
+
+	w := pipeline(t)
+	w.Step("merge").Destination = "missing"
+	err := w.Submit(client)
+	var werr *WorkflowError
+	if !errors.As(err, &werr) || werr.Step != "merge" || !errors.Is(err, ErrUnknownQueue) || len(werr.Rollback) != 0 {
+		t.Fatalf("Submit returned %v\n", err)
+	}
+	if ids := w.JobIDs(); len(ids) != 0 {
+		t.Errorf("JobIDs after a rollback returned %v\n", ids)
+	}
+	if status, err := client.StatJob("", nil, ""); len(status) != 0 || err != nil {
+		t.Errorf("after a rollback the server has %d jobs, %v\n", len(status), err)
+	}
+}
+
+func TestWorkflowDependTypes(t *testing.T) {
+	for _, depend := range []DependType{
+		DependBefore, DependBeforeOK, DependBeforeNotOK, DependBeforeAny,
+		DependBeforeStartArray, DependBeforeOKArray, DependBeforeNotOKArray, DependBeforeAnyArray,
+		DependOn, DependSyncWith, DependSyncCount, "unknown",
+	} {
+		w := NewWorkflow()
+		if err := w.Add(Step{Name: "child", After: []string{"parent"}, Depend: depend}); err == nil {
+			t.Errorf("adding a step with the %s dependency type didn't fail\n", depend)
+		}
+		if w.Step("child") != nil {
+			t.Errorf("the step with the %s dependency type was added\n", depend)
+		}
+	}
+}
+
+// undeletableClient fails to delete the job id
+type undeletableClient struct {
+	Client
+	id string
+}
+
+func (c undeletableClient) DelJob(id string, extend string) error {
+	if id == c.id {
+		return &PBSError{Op: "pbs_deljob", ID: id, Errno: PBSE_PERM}
+	}
+	return c.Client.DelJob(id, extend)
+}
+
+func TestWorkflowRollbackFailure(t *testing.T) {
+	server := NewFakeServer("fake")
+	client := connectFake(t, server)
+
+	w := pipeline(t)
+	w.Step("merge").Destination = "missing"
+	err := w.Submit(undeletableClient{Client: client, id: "2.fake"})
+	var werr *WorkflowError
+	if !errors.As(err, &werr) || len(werr.Rollback) != 1 || !reflect.DeepEqual(werr.Undeleted, []string{"2.fake"}) {
+		t.Fatalf("Submit returned %v\n", err)
+	}
+	if !strings.Contains(err.Error(), "2.fake") {
+		t.Errorf("the error %q doesn't have the undeleted job's ID\n", err)
+	}
+	if ids := w.JobIDs(); !reflect.DeepEqual(ids, map[string]string{"left": "2.fake"}) {
+		t.Errorf("JobIDs after a failed rollback returned %v\n", ids)
+	}
+}